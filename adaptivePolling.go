@@ -0,0 +1,74 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "context"
+    "database/sql"
+    "log"
+    "time"
+)
+
+// recentPostSample is how many of a feed's latest posts the posting
+// frequency is measured over.
+const recentPostSample = 20
+
+// observedPostInterval estimates how often a feed publishes from its latest
+// post times, newest first. The silence since the newest post counts as a
+// lower bound so feeds that have gone quiet slow down over time. It returns
+// 0 when there isn't enough history to say.
+func observedPostInterval(published []time.Time, now time.Time) time.Duration {
+
+  if len(published) < 2 {
+    return 0
+  }
+
+  newest := published[0]
+  oldest := published[len(published)-1]
+  interval := newest.Sub(oldest) / time.Duration(len(published)-1)
+
+  if silence := now.Sub(newest); silence > interval {
+    interval = silence
+  }
+
+  return interval
+}
+
+// updatePostInterval recomputes and stores the feed's observed posting
+// interval, returning it for scheduling.
+func updatePostInterval(ctx context.Context, s *state, fetchedFeed database.Feed, now time.Time) time.Duration {
+
+  times, err := s.db.GetRecentPostTimes(
+    ctx,
+    database.GetRecentPostTimesParams{
+      FeedID: fetchedFeed.ID,
+      Limit:  recentPostSample,
+    },
+  )
+  if err != nil {
+    log.Printf("Error getting post times for feed %s: %v", fetchedFeed.Url, err)
+    return time.Duration(fetchedFeed.PostIntervalSeconds.Int64) * time.Second
+  }
+
+  var published []time.Time
+  for _, t := range times {
+    published = append(published, t.Time)
+  }
+
+  interval := observedPostInterval(published, now)
+
+  err = s.db.SetFeedPostInterval(
+    ctx,
+    database.SetFeedPostIntervalParams{
+      ID: fetchedFeed.ID,
+      PostIntervalSeconds: sql.NullInt64{
+        Int64: int64(interval / time.Second),
+        Valid: interval > 0,
+      },
+    },
+  )
+  if err != nil {
+    log.Printf("Error saving post interval for feed %s: %v", fetchedFeed.Url, err)
+  }
+
+  return interval
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+    "strings"
+)
+
+type atomFeed struct {
+  Lang      string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+  Title     atomText    `xml:"title"`
+  Subtitle  atomText    `xml:"subtitle"`
+  Link      []atomLink  `xml:"link"`
+  Icon      string      `xml:"icon"`
+  Logo      string      `xml:"logo"`
+  Generator string      `xml:"generator"`
+  Entry     []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+  ID        string     `xml:"id"`
+  Title     atomText   `xml:"title"`
+  Link      []atomLink `xml:"link"`
+  Updated   string     `xml:"updated"`
+  Published string     `xml:"published"`
+  Summary   atomText   `xml:"summary"`
+  Content   atomText   `xml:"content"`
+  Author    []atomPerson   `xml:"author"`
+  Category  []atomCategory `xml:"category"`
+}
+
+type atomPerson struct {
+  Name string `xml:"name"`
+}
+
+type atomCategory struct {
+  Term  string `xml:"term,attr"`
+  Label string `xml:"label,attr"`
+}
+
+type atomLink struct {
+  Href string `xml:"href,attr"`
+  Rel  string `xml:"rel,attr"`
+  Type   string `xml:"type,attr"`
+  Length string `xml:"length,attr"`
+}
+
+// atomText covers Atom text constructs, which hold either plain/escaped
+// text or, with type="xhtml", inline markup.
+type atomText struct {
+  Type     string `xml:"type,attr"`
+  Text     string `xml:",chardata"`
+  InnerXML string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+  if t.Type == "xhtml" {
+    return strings.TrimSpace(t.InnerXML)
+  }
+  return strings.TrimSpace(t.Text)
+}
+
+// alternateLink returns the rel="alternate" href, which is also what a link
+// with no rel means in Atom, falling back to the first link given.
+func alternateLink(links []atomLink) string {
+  for _, link := range links {
+    if link.Rel == "" || link.Rel == "alternate" {
+      return link.Href
+    }
+  }
+
+  if len(links) > 0 {
+    return links[0].Href
+  }
+  return ""
+}
+
+func parseAtom(body []byte) (*RSSFeed, error) {
+
+  var atom atomFeed
+
+  err := unmarshalXML(body, &atom)
+  if err != nil {
+    return nil, err
+  }
+
+  var rssFeed RSSFeed
+  rssFeed.Channel.Title = atom.Title.String()
+  rssFeed.Channel.Link = alternateLink(atom.Link)
+  rssFeed.Channel.AtomLinks = atom.Link
+  rssFeed.Channel.Description = atom.Subtitle.String()
+  rssFeed.Channel.Language = atom.Lang
+  rssFeed.Channel.Generator = atom.Generator
+
+  // The logo is the bigger image; the icon is usually a favicon.
+  rssFeed.Channel.Images = []rssImage{{URL: atom.Logo}, {URL: atom.Icon}}
+
+  for _, entry := range atom.Entry {
+    description := entry.Summary.String()
+    if description == "" {
+      description = entry.Content.String()
+    }
+
+    pubDate := entry.Published
+    if pubDate == "" {
+      pubDate = entry.Updated
+    }
+
+    item := RSSItem{
+      Title:       entry.Title.String(),
+      Link:        alternateLink(entry.Link),
+      Description: description,
+      PubDate:     strings.TrimSpace(pubDate),
+      Content:     entry.Content.String(),
+      GUID:        strings.TrimSpace(entry.ID),
+    }
+
+    for _, author := range entry.Author {
+      item.Authors = append(item.Authors, author.Name)
+    }
+    for _, category := range entry.Category {
+      if category.Label != "" {
+        item.Categories = append(item.Categories, category.Label)
+      } else {
+        item.Categories = append(item.Categories, category.Term)
+      }
+    }
+    for _, link := range entry.Link {
+      if link.Rel == "enclosure" {
+        item.Enclosures = append(item.Enclosures, RSSEnclosure{URL: link.Href, Type: link.Type, Length: link.Length})
+      }
+    }
+
+    rssFeed.Channel.Item = append(rssFeed.Channel.Item, item)
+  }
+
+  return &rssFeed, nil
+}
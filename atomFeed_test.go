@@ -0,0 +1,98 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+const sampleAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en-us">
+  <title type="text">Example Blog</title>
+  <subtitle>Notes from an example</subtitle>
+  <link rel="self" href="https://example.com/atom.xml"/>
+  <link href="https://example.com/"/>
+  <icon>https://example.com/favicon.ico</icon>
+  <logo>https://example.com/logo.png</logo>
+  <generator>Hugo</generator>
+  <entry>
+    <id>tag:example.com,2024:1</id>
+    <title>First &amp; foremost</title>
+    <link rel="alternate" href="https://example.com/first"/>
+    <link rel="enclosure" href="https://example.com/first.mp3" type="audio/mpeg" length="1234"/>
+    <published>2024-01-02T10:00:00Z</published>
+    <updated>2024-01-03T10:00:00Z</updated>
+    <summary>A teaser</summary>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>The whole post</p></div></content>
+    <author><name>Ada</name></author>
+    <author><name>Grace</name></author>
+    <category term="go"/>
+    <category term="web" label="The Web"/>
+  </entry>
+  <entry>
+    <id>tag:example.com,2024:2</id>
+    <title type="html">&lt;b&gt;Second&lt;/b&gt;</title>
+    <link href="https://example.com/second"/>
+    <updated>2024-02-01T08:30:00+01:00</updated>
+    <content type="html">&lt;p&gt;Only content&lt;/p&gt;</content>
+  </entry>
+</feed>`
+
+func TestParseAtom(t *testing.T) {
+
+  feed, err := parseFeed([]byte(sampleAtom), "application/atom+xml")
+  if err != nil {
+    t.Fatalf("parseFeed: %v", err)
+  }
+
+  channel := feed.Channel
+  if channel.Title != "Example Blog" || channel.Description != "Notes from an example" {
+    t.Errorf("title, description = %q, %q", channel.Title, channel.Description)
+  }
+  if channel.Link != "https://example.com/" {
+    t.Errorf("link = %q; want the alternate link", channel.Link)
+  }
+  if selfLink(feed) != "https://example.com/atom.xml" {
+    t.Errorf("selfLink = %q", selfLink(feed))
+  }
+  if channel.Language != "en-us" || channel.Generator != "Hugo" {
+    t.Errorf("language, generator = %q, %q", channel.Language, channel.Generator)
+  }
+  wantImages := []rssImage{{URL: "https://example.com/logo.png"}, {URL: "https://example.com/favicon.ico"}}
+  if !reflect.DeepEqual(channel.Images, wantImages) {
+    t.Errorf("images = %v; want %v", channel.Images, wantImages)
+  }
+
+  if len(channel.Item) != 2 {
+    t.Fatalf("got %d entries; want 2", len(channel.Item))
+  }
+
+  first := channel.Item[0]
+  wantFirst := RSSItem{
+    Title:       "First & foremost",
+    Link:        "https://example.com/first",
+    Description: "A teaser",
+    PubDate:     "2024-01-02T10:00:00Z",
+    GUID:        "tag:example.com,2024:1",
+    Content:     `<div xmlns="http://www.w3.org/1999/xhtml"><p>The whole post</p></div>`,
+    Authors:     []string{"Ada", "Grace"},
+    Categories:  []string{"go", "The Web"},
+    Enclosures: []RSSEnclosure{
+      {URL: "https://example.com/first.mp3", Type: "audio/mpeg", Length: "1234"},
+    },
+  }
+  if !reflect.DeepEqual(first, wantFirst) {
+    t.Errorf("first entry = %+v\nwant %+v", first, wantFirst)
+  }
+
+  // Without a summary or published date, content and updated stand in.
+  second := channel.Item[1]
+  if second.Title != "<b>Second</b>" || second.Link != "https://example.com/second" {
+    t.Errorf("second entry title, link = %q, %q", second.Title, second.Link)
+  }
+  if second.Description != "<p>Only content</p>" || second.Content != "<p>Only content</p>" {
+    t.Errorf("second entry description, content = %q, %q", second.Description, second.Content)
+  }
+  if second.PubDate != "2024-02-01T08:30:00+01:00" {
+    t.Errorf("second entry pubDate = %q", second.PubDate)
+  }
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// httpURLParts splits an http(s) URL into host, path and query, dropping
+// the fragment. It works on the raw string rather than net/url, which
+// rejects or re-escapes some of what turns up in feeds, and so that the SQL
+// copy can do exactly the same. Escaping is left alone: %7E and ~ give
+// different keys.
+var httpURLParts = regexp.MustCompile(`^(?i:https?)://([^/?#]*)([^?#]*)(?:\?([^#]*))?`)
+
+// trackingParam matches query parameters that only say where a click came
+// from.
+var trackingParam = regexp.MustCompile(`(?i)^(utm_[^=]*|fbclid|gclid|mc_cid|mc_eid)(=|$)`)
+
+// canonicalURL is the form two URLs for the same resource share, used to
+// tell whether a feed or post is already known. It ignores http vs https, a
+// leading www., host case, default ports, trailing slashes, the fragment,
+// tracking parameters and the order of the rest. It is an identity key, not
+// something to fetch: the site may well not serve https or drop the www.
+// Anything that isn't an http(s) URL is only trimmed.
+//
+// Migrations 021 and 023 backfill existing rows with SQL copies of this
+// function; keep them in step.
+func canonicalURL(raw string) string {
+
+  raw = strings.TrimSpace(raw)
+
+  parts := httpURLParts.FindStringSubmatch(raw)
+  if parts == nil {
+    return raw
+  }
+
+  host := strings.ToLower(parts[1])
+  host = strings.TrimPrefix(host, "www.")
+  host = strings.TrimSuffix(strings.TrimSuffix(host, ":80"), ":443")
+
+  path := strings.TrimRight(parts[2], "/")
+
+  var params []string
+  for _, param := range strings.Split(parts[3], "&") {
+    if param != "" && !trackingParam.MatchString(param) {
+      params = append(params, param)
+    }
+  }
+  sort.Strings(params)
+
+  canonical := "https://" + host + path
+  if len(params) > 0 {
+    canonical += "?" + strings.Join(params, "&")
+  }
+  return canonical
+}
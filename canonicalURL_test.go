@@ -0,0 +1,169 @@
+package main
+
+import (
+    "database/sql"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+var canonicalURLTests = []struct {
+  raw  string
+  want string
+}{
+  {"https://example.com/feed", "https://example.com/feed"},
+  {"http://Example.COM/feed/", "https://example.com/feed"},
+  {"  https://www.example.com:443/a/?b=2&a=1#top ", "https://example.com/a?a=1&b=2"},
+  {"http://example.com:80", "https://example.com"},
+  {"https://example.com:8080/x", "https://example.com:8080/x"},
+  {"https://example.com/p?utm_source=x&id=3&fbclid=y&UTM_Medium=z", "https://example.com/p?id=3"},
+  {"https://example.com/p?utm_source=x", "https://example.com/p"},
+  {"https://example.com/p?gclidx=1&&mc_cid", "https://example.com/p?gclidx=1"},
+  {"HTTPS://example.com/%7Euser", "https://example.com/%7Euser"},
+  {"https://example.com/~user", "https://example.com/~user"},
+  {"https://example.com/Case/Path", "https://example.com/Case/Path"},
+  {"ftp://example.com/x", "ftp://example.com/x"},
+  {" tag:example.com,2024:post-1 ", "tag:example.com,2024:post-1"},
+  {"", ""},
+}
+
+func TestCanonicalURL(t *testing.T) {
+
+  for _, test := range canonicalURLTests {
+    got := canonicalURL(test.raw)
+    if got != test.want {
+      t.Errorf("canonicalURL(%q) = %q; want %q", test.raw, got, test.want)
+    }
+  }
+}
+
+// migrationFunctions returns, by file name, each migration's CREATE FUNCTION
+// statement for its copy of gator_canonical_url.
+func migrationFunctions(t *testing.T) map[string]string {
+
+  files, err := filepath.Glob("sql/schema/*.sql")
+  if err != nil {
+    t.Fatalf("listing migrations: %v", err)
+  }
+
+  functions := map[string]string{}
+  for _, file := range files {
+    body, err := os.ReadFile(file)
+    if err != nil {
+      t.Fatalf("reading %s: %v", file, err)
+    }
+
+    _, statement, found := strings.Cut(string(body), "CREATE FUNCTION gator_canonical_url")
+    if !found {
+      continue
+    }
+    statement, _, ok := strings.Cut(statement, "-- +goose StatementEnd")
+    if !ok {
+      t.Fatalf("%s has no end to gator_canonical_url", file)
+    }
+    functions[filepath.Base(file)] = "CREATE FUNCTION gator_canonical_url" + statement
+  }
+
+  if functions["021_canonical_urls.sql"] == "" {
+    t.Fatalf("migration 021 no longer defines gator_canonical_url")
+  }
+  return functions
+}
+
+// TestCanonicalURLMigrationPatterns checks that the patterns the SQL copies
+// in the migrations use split URLs and spot tracking parameters the same way
+// canonicalURL does.
+func TestCanonicalURLMigrationPatterns(t *testing.T) {
+
+  for file, function := range migrationFunctions(t) {
+    t.Run(file, func(t *testing.T) {
+      checkMigrationPatterns(t, function)
+    })
+  }
+}
+
+func checkMigrationPatterns(t *testing.T, function string) {
+
+  urlPattern := regexp.MustCompile(`regexp_match\(.*, '([^']*)'\);`).FindStringSubmatch(function)
+  trackingPattern := regexp.MustCompile(`!~\* '([^']*)'`).FindStringSubmatch(function)
+  if urlPattern == nil || trackingPattern == nil {
+    t.Fatalf("could not find the URL and tracking patterns")
+  }
+
+  sqlURLParts := regexp.MustCompile(urlPattern[1])
+  // !~* matches case-insensitively.
+  sqlTrackingParam := regexp.MustCompile("(?i)" + trackingPattern[1])
+
+  for _, test := range canonicalURLTests {
+    raw := strings.TrimSpace(test.raw)
+
+    goParts := httpURLParts.FindStringSubmatch(raw)
+    sqlParts := sqlURLParts.FindStringSubmatch(raw)
+    if strings.Join(goParts, "\x00") != strings.Join(sqlParts, "\x00") {
+      t.Errorf("%q splits as %q in Go but %q in SQL", raw, goParts, sqlParts)
+      continue
+    }
+    if goParts == nil {
+      continue
+    }
+
+    for _, param := range strings.Split(goParts[3], "&") {
+      if trackingParam.MatchString(param) != sqlTrackingParam.MatchString(param) {
+        t.Errorf("Go and SQL disagree on whether %q is a tracking parameter", param)
+      }
+    }
+  }
+
+  for _, strip := range []string{`'^www\.'`, `':80$'`, `':443$'`, `lower(parts[1])`, `rtrim(parts[2], '/')`, `COLLATE "C"`, `'https://' ||`} {
+    if !strings.Contains(function, strip) {
+      t.Errorf("gator_canonical_url no longer contains %s", strip)
+    }
+  }
+}
+
+// TestCanonicalURLMigrationDatabase runs the SQL copies themselves when
+// GATOR_TEST_DB_URL names a database to try it in.
+func TestCanonicalURLMigrationDatabase(t *testing.T) {
+
+  dbURL := os.Getenv("GATOR_TEST_DB_URL")
+  if dbURL == "" {
+    t.Skip("GATOR_TEST_DB_URL is not set")
+  }
+
+  db, err := sql.Open("postgres", dbURL)
+  if err != nil {
+    t.Fatalf("opening database: %v", err)
+  }
+  defer db.Close()
+
+  tx, err := db.Begin()
+  if err != nil {
+    t.Fatalf("starting transaction: %v", err)
+  }
+  defer tx.Rollback()
+
+  for file, function := range migrationFunctions(t) {
+    function = strings.Replace(function, "gator_canonical_url", "pg_temp.gator_canonical_url", 1)
+    _, err = tx.Exec("DROP FUNCTION IF EXISTS pg_temp.gator_canonical_url(TEXT)")
+    if err != nil {
+      t.Fatalf("dropping gator_canonical_url: %v", err)
+    }
+    _, err = tx.Exec(function)
+    if err != nil {
+      t.Fatalf("creating gator_canonical_url from %s: %v", file, err)
+    }
+
+    for _, test := range canonicalURLTests {
+      var got string
+      err := tx.QueryRow("SELECT pg_temp.gator_canonical_url($1)", test.raw).Scan(&got)
+      if err != nil {
+        t.Fatalf("%s: gator_canonical_url(%q): %v", file, test.raw, err)
+      }
+      if got != canonicalURL(test.raw) {
+        t.Errorf("%s: gator_canonical_url(%q) = %q; canonicalURL gives %q", file, test.raw, got, canonicalURL(test.raw))
+      }
+    }
+  }
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "mime"
+    "regexp"
+    "strings"
+    "unicode/utf16"
+    "unicode/utf8"
+)
+
+// Labels for the charsets we can transcode, as used in XML declarations and
+// Content-Type headers.
+var charsetTables = map[string]*[128]rune{
+  "windows-1250": &windows1250High,
+  "cp1250":       &windows1250High,
+  "windows-1251": &windows1251High,
+  "cp1251":       &windows1251High,
+  "windows-1252": &windows1252High,
+  "cp1252":       &windows1252High,
+  "iso-8859-5":   &iso88595High,
+  "iso-8859-15":  &iso885915High,
+  "latin-9":      &iso885915High,
+  "koi8-r":       &koi8rHigh,
+}
+
+var xmlDeclEncoding = regexp.MustCompile(`^(<\?xml[^>]*?)\s+encoding\s*=\s*["'][^"']*["']`)
+
+// decodeCharset returns a function that converts text in the named charset
+// to UTF-8, or false when the charset is not one we know.
+func decodeCharset(label string) (func([]byte) []byte, bool) {
+
+  label = strings.ToLower(strings.TrimSpace(label))
+
+  switch label {
+  case "", "utf-8", "utf8", "us-ascii", "ascii":
+    return func(data []byte) []byte { return data }, true
+  case "iso-8859-1", "latin1", "latin-1", "l1", "iso8859-1":
+    return decodeLatin1, true
+  }
+
+  table, ok := charsetTables[label]
+  if !ok {
+    return nil, false
+  }
+
+  return func(data []byte) []byte {
+    var out bytes.Buffer
+    out.Grow(len(data))
+    for _, b := range data {
+      if b < 0x80 {
+        out.WriteByte(b)
+      } else {
+        out.WriteRune(table[b-0x80])
+      }
+    }
+    return out.Bytes()
+  }, true
+}
+
+func decodeLatin1(data []byte) []byte {
+  var out bytes.Buffer
+  out.Grow(len(data))
+  for _, b := range data {
+    out.WriteRune(rune(b))
+  }
+  return out.Bytes()
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+  units := make([]uint16, 0, len(data)/2)
+  for i := 0; i+1 < len(data); i += 2 {
+    units = append(units, order.Uint16(data[i:]))
+  }
+
+  var out bytes.Buffer
+  for _, r := range utf16.Decode(units) {
+    out.WriteRune(r)
+  }
+  return out.Bytes()
+}
+
+// charsetReader lets xml.Decoder read documents whose XML declaration names
+// a charset other than UTF-8.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+
+  decode, ok := decodeCharset(label)
+  if !ok {
+    return nil, fmt.Errorf("unsupported charset: %s", label)
+  }
+
+  data, err := io.ReadAll(input)
+  if err != nil {
+    return nil, err
+  }
+  return bytes.NewReader(decode(data)), nil
+}
+
+// toUTF8 transcodes a response body using its byte order mark or, failing
+// that, the charset in the Content-Type header, both of which outrank the
+// XML declaration. The declaration's encoding is dropped once the body is
+// UTF-8 so the parser doesn't decode it twice. Bodies with neither are left
+// for charsetReader to handle.
+func toUTF8(body []byte, contentType string) ([]byte, error) {
+
+  switch {
+  case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+    return stripXMLEncoding(body[3:]), nil
+  case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+    return stripXMLEncoding(decodeUTF16(body[2:], binary.LittleEndian)), nil
+  case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+    return stripXMLEncoding(decodeUTF16(body[2:], binary.BigEndian)), nil
+  }
+
+  _, params, err := mime.ParseMediaType(contentType)
+  if err != nil || params["charset"] == "" {
+    return body, nil
+  }
+
+  label := params["charset"]
+  if strings.EqualFold(label, "utf-8") && utf8.Valid(body) {
+    return stripXMLEncoding(body), nil
+  }
+
+  decode, ok := decodeCharset(label)
+  if !ok {
+    return nil, fmt.Errorf("unsupported charset: %s", label)
+  }
+  return stripXMLEncoding(decode(body)), nil
+}
+
+func stripXMLEncoding(body []byte) []byte {
+  return xmlDeclEncoding.ReplaceAll(body, []byte("$1"))
+}
+
+// unmarshalXML is xml.Unmarshal with charset support.
+func unmarshalXML(body []byte, v any) error {
+  decoder := xml.NewDecoder(bytes.NewReader(body))
+  decoder.CharsetReader = charsetReader
+  return decoder.Decode(v)
+}
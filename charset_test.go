@@ -0,0 +1,82 @@
+package main
+
+import (
+    "testing"
+)
+
+func TestDecodeCharset(t *testing.T) {
+
+  tests := []struct {
+    label string
+    data  []byte
+    want  string
+  }{
+    {"UTF-8", []byte("héllo"), "héllo"},
+    {"iso-8859-1", []byte{'c', 'a', 'f', 0xE9}, "café"},
+    {" Windows-1251 ", []byte{0xCF, 0xF0, 0xE8, 0xE2, 0xE5, 0xF2}, "Привет"},
+    {"cp1252", []byte{0x80, ' ', 0x93, 'q', 0x94}, "€ “q”"},
+    {"koi8-r", []byte{0xED, 0xC9, 0xD2}, "Мир"},
+    {"iso-8859-15", []byte{0xA4}, "€"},
+  }
+
+  for _, test := range tests {
+    decode, ok := decodeCharset(test.label)
+    if !ok {
+      t.Errorf("decodeCharset(%q) is not supported", test.label)
+      continue
+    }
+    got := string(decode(test.data))
+    if got != test.want {
+      t.Errorf("decodeCharset(%q) gave %q; want %q", test.label, got, test.want)
+    }
+  }
+
+  _, ok := decodeCharset("shift_jis")
+  if ok {
+    t.Errorf("decodeCharset(shift_jis) should not be supported")
+  }
+}
+
+func TestToUTF8(t *testing.T) {
+
+  // "Мир" in windows-1251, declared both in the header and the document.
+  declared := append([]byte(`<?xml version="1.0" encoding="windows-1251"?><rss><channel><title>`), 0xCC, 0xE8, 0xF0)
+  declared = append(declared, []byte(`</title></channel></rss>`)...)
+
+  body, err := toUTF8(declared, "application/rss+xml; charset=windows-1251")
+  if err != nil {
+    t.Fatalf("toUTF8: %v", err)
+  }
+  want := `<?xml version="1.0"?><rss><channel><title>Мир</title></channel></rss>`
+  if string(body) != want {
+    t.Errorf("toUTF8 = %q; want %q", body, want)
+  }
+
+  // With no charset in the header, the XML declaration decides.
+  body, err = toUTF8(declared, "application/rss+xml")
+  if err != nil {
+    t.Fatalf("toUTF8 without a charset: %v", err)
+  }
+  feed, err := parseFeed(body, "application/rss+xml")
+  if err != nil {
+    t.Fatalf("parseFeed: %v", err)
+  }
+  if feed.Channel.Title != "Мир" {
+    t.Errorf("title = %q; want %q", feed.Channel.Title, "Мир")
+  }
+
+  // A byte order mark outranks the header.
+  utf16 := []byte{0xFF, 0xFE, '<', 0, 'a', 0, '>', 0, 0x1F, 0x04, '<', 0, '/', 0, 'a', 0, '>', 0}
+  body, err = toUTF8(utf16, "text/xml; charset=iso-8859-1")
+  if err != nil {
+    t.Fatalf("toUTF8 with a BOM: %v", err)
+  }
+  if string(body) != "<a>П</a>" {
+    t.Errorf("toUTF8 with a BOM = %q; want %q", body, "<a>П</a>")
+  }
+
+  _, err = toUTF8([]byte("<a/>"), "text/xml; charset=shift_jis")
+  if err == nil {
+    t.Errorf("toUTF8 should reject an unsupported charset")
+  }
+}
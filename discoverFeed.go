@@ -0,0 +1,270 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "html"
+    "net/http"
+    "net/url"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// feedLinkTypes are the <link type> values that announce a feed.
+// Plain application/json is left out: WordPress uses it for its REST API.
+var feedLinkTypes = map[string]bool{
+  "application/rss+xml":   true,
+  "application/atom+xml":  true,
+  "application/feed+json": true,
+}
+
+// commonFeedPaths are tried on the site root when a page doesn't link to
+// its feed.
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml"}
+
+var (
+  htmlLinkTag   = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+  htmlAttribute = regexp.MustCompile(`(?s)([a-zA-Z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+type feedCandidate struct {
+  URL   string
+  Title string
+  Type  string
+}
+
+// fetchedPage is whatever addfeed was pointed at: a feed, or a page that
+// might lead to one.
+type fetchedPage struct {
+  URL         string
+  // FinalURL is where the page came from once redirects were followed;
+  // URL may be set to what was asked for instead.
+  FinalURL    string
+  Body        []byte
+  ContentType string
+  Feed        *RSSFeed
+  ParseErr    error
+}
+
+func fetchPage(ctx context.Context, client *feedClient, pageURL string) (fetchedPage, error) {
+
+  req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+  if err != nil {
+    return fetchedPage{}, err
+  }
+  req.Header.Set("User-Agent", client.userAgent)
+
+  rsp, err := client.http.Do(req)
+  if err != nil {
+    return fetchedPage{}, err
+  }
+  defer rsp.Body.Close()
+
+  if rsp.StatusCode != http.StatusOK {
+    return fetchedPage{}, fmt.Errorf("server answered %s", rsp.Status)
+  }
+
+  body, err := client.readBody(rsp.Body)
+  if err != nil {
+    return fetchedPage{}, err
+  }
+
+  page := fetchedPage{
+    URL:         rsp.Request.URL.String(),
+    FinalURL:    rsp.Request.URL.String(),
+    ContentType: rsp.Header.Get("Content-Type"),
+  }
+
+  page.Body, err = toUTF8(body, page.ContentType)
+  if err != nil {
+    return fetchedPage{}, err
+  }
+
+  // Anything that parses as a feed is one, whatever the server calls it.
+  page.Feed, page.ParseErr = parseFeed(page.Body, page.ContentType)
+  if page.Feed != nil {
+    unescapeFeed(page.Feed)
+  }
+  return page, nil
+}
+
+// isHTML reports whether a page that isn't a feed is worth looking through
+// for feed links.
+func isHTML(page fetchedPage) bool {
+  if strings.Contains(strings.ToLower(page.ContentType), "html") {
+    return true
+  }
+
+  start := strings.ToLower(strings.TrimSpace(string(page.Body)))
+  return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html")
+}
+
+// discoverFeeds finds the feeds an HTML page announces with
+// <link rel="alternate">, falling back to probing the usual feed paths on
+// its site.
+func discoverFeeds(ctx context.Context, client *feedClient, page fetchedPage) []feedCandidate {
+
+  base, err := url.Parse(page.URL)
+  if err != nil {
+    return nil
+  }
+
+  var candidates []feedCandidate
+  seen := map[string]bool{}
+
+  for _, tag := range htmlLinkTag.FindAllString(string(page.Body), -1) {
+    attrs := htmlAttributes(tag)
+
+    if !hasToken(attrs["rel"], "alternate") || !feedLinkTypes[strings.ToLower(attrs["type"])] {
+      continue
+    }
+
+    href, err := base.Parse(strings.TrimSpace(attrs["href"]))
+    if err != nil || attrs["href"] == "" || seen[href.String()] {
+      continue
+    }
+    seen[href.String()] = true
+
+    candidates = append(candidates, feedCandidate{
+      URL:   href.String(),
+      Title: strings.TrimSpace(attrs["title"]),
+      Type:  strings.ToLower(attrs["type"]),
+    })
+  }
+
+  if len(candidates) > 0 {
+    return candidates
+  }
+
+  for _, feedPath := range commonFeedPaths {
+    probe := base.ResolveReference(&url.URL{Path: feedPath})
+
+    found, err := fetchPage(ctx, client, probe.String())
+    if err != nil || found.Feed == nil {
+      continue
+    }
+
+    candidates = append(candidates, feedCandidate{
+      URL:   found.URL,
+      Title: strings.TrimSpace(found.Feed.Channel.Title),
+    })
+  }
+
+  return candidates
+}
+
+func htmlAttributes(tag string) map[string]string {
+
+  attrs := map[string]string{}
+  for _, match := range htmlAttribute.FindAllStringSubmatch(tag, -1) {
+    value := match[2] + match[3] + match[4]
+    attrs[strings.ToLower(match[1])] = html.UnescapeString(value)
+  }
+  return attrs
+}
+
+func hasToken(list, token string) bool {
+  for _, field := range strings.Fields(list) {
+    if strings.EqualFold(field, token) {
+      return true
+    }
+  }
+  return false
+}
+
+// chooseFeed returns the only candidate, or asks which one to use when the
+// page offers several, e.g. posts and comments.
+func chooseFeed(candidates []feedCandidate) (feedCandidate, error) {
+
+  if len(candidates) == 1 {
+    return candidates[0], nil
+  }
+
+  fmt.Println("Found several feeds:")
+  for i, candidate := range candidates {
+    label := candidate.URL
+    if candidate.Title != "" {
+      label = fmt.Sprintf("%s (%s)", candidate.Title, candidate.URL)
+    }
+    fmt.Printf("  %d) %s\n", i+1, label)
+  }
+  fmt.Printf("Pick a feed [1-%d]: ", len(candidates))
+
+  line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+  if err != nil && line == "" {
+    return feedCandidate{}, fmt.Errorf("no feed picked")
+  }
+
+  choice, err := strconv.Atoi(strings.TrimSpace(line))
+  if err != nil || choice < 1 || choice > len(candidates) {
+    return feedCandidate{}, fmt.Errorf("invalid choice: %s", strings.TrimSpace(line))
+  }
+  return candidates[choice-1], nil
+}
+
+// resolveFeed test-fetches what was passed to addfeed and returns the feed
+// it is, or the feed a page leads to, so nothing that doesn't parse ends up
+// in feeds.
+func resolveFeed(ctx context.Context, client *feedClient, pageURL string) (fetchedPage, error) {
+
+  page, err := fetchPage(ctx, client, pageURL)
+  if err != nil {
+    return fetchedPage{}, fmt.Errorf("couldn't fetch %s: %w", pageURL, err)
+  }
+
+  if page.Feed != nil {
+    page.URL = pageURL
+    return page, nil
+  }
+
+  if !isHTML(page) {
+    return fetchedPage{}, notAFeed(page)
+  }
+
+  candidates := discoverFeeds(ctx, client, page)
+  if len(candidates) == 0 {
+    return fetchedPage{}, fmt.Errorf("no feed found on %s: it is a web page (%s) that doesn't link to one", pageURL, describeContentType(page.ContentType))
+  }
+
+  candidate, err := chooseFeed(candidates)
+  if err != nil {
+    return fetchedPage{}, err
+  }
+
+  feedPage, err := fetchPage(ctx, client, candidate.URL)
+  if err != nil {
+    return fetchedPage{}, fmt.Errorf("couldn't fetch %s: %w", candidate.URL, err)
+  }
+  if feedPage.Feed == nil {
+    return fetchedPage{}, notAFeed(feedPage)
+  }
+
+  feedPage.URL = candidate.URL
+  return feedPage, nil
+}
+
+// feedName is what a feed added without a name is called: its own title,
+// or failing that the host it is on.
+func feedName(page fetchedPage) string {
+  if title := strings.TrimSpace(page.Feed.Channel.Title); title != "" {
+    return title
+  }
+  if parsed, err := url.Parse(page.URL); err == nil && parsed.Host != "" {
+    return parsed.Host
+  }
+  return page.URL
+}
+
+func notAFeed(page fetchedPage) error {
+  return fmt.Errorf("%s is not a feed (%s): %v", page.URL, describeContentType(page.ContentType), page.ParseErr)
+}
+
+func describeContentType(contentType string) string {
+  if contentType == "" {
+    return "no content type"
+  }
+  return "content type " + contentType
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+    "context"
+    "reflect"
+    "testing"
+)
+
+func TestDiscoverFeedsLinks(t *testing.T) {
+
+  page := fetchedPage{
+    URL:         "https://example.com/blog/",
+    ContentType: "text/html; charset=utf-8",
+    Body: []byte(`<!doctype html><html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed/">
+<link rel='alternate' type='application/atom+xml' href='https://example.com/atom.xml'>
+<link rel="alternate" type="application/feed+json" href="feed.json">
+<link rel="alternate" type="application/json" href="https://example.com/wp-json/wp/v2/pages/2">
+<link rel="https://api.w.org/" href="https://example.com/wp-json/">
+<link rel="alternate" type="application/rss+xml" href="/feed/">
+</head><body></body></html>`),
+  }
+
+  // Links are found, so no client is needed to probe for feeds.
+  got := discoverFeeds(context.Background(), nil, page)
+  want := []feedCandidate{
+    {URL: "https://example.com/feed/", Title: "Posts", Type: "application/rss+xml"},
+    {URL: "https://example.com/atom.xml", Type: "application/atom+xml"},
+    {URL: "https://example.com/blog/feed.json", Type: "application/feed+json"},
+  }
+  if !reflect.DeepEqual(got, want) {
+    t.Errorf("discoverFeeds = %+v\nwant %+v", got, want)
+  }
+}
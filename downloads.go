@@ -0,0 +1,649 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "mime"
+    "net/http"
+    "net/url"
+    "os"
+    "os/signal"
+    "path"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+    "unicode"
+    "github.com/google/uuid"
+)
+
+func handlerDownload(s *state, cmd command, user database.User) error {
+  if len(cmd.args) == 0 {
+    return fmt.Errorf("expected feed url, optionally followed by how many episodes to keep or off")
+  }
+
+  feedURL := cmd.args[0]
+
+  feedID, err := s.db.GetFeedByUrl(context.Background(), canonicalURL(feedURL))
+  if err != nil {
+    return fmt.Errorf("Error getting feed: %w", err)
+  }
+
+  follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+  if err != nil {
+    return fmt.Errorf("Error getting follows: %w", err)
+  }
+
+  following := false
+  for _, follow := range follows {
+    if follow.FeedID == feedID {
+      following = true
+    }
+  }
+  if !following {
+    return fmt.Errorf("you need to follow %s to download it", feedURL)
+  }
+
+  if len(cmd.args) > 1 && cmd.args[1] == "off" {
+    return disableDownloads(s, feedID, feedURL)
+  }
+
+  keep := s.cfg.DownloadRetention()
+  if len(cmd.args) > 1 {
+    keep, err = strconv.Atoi(cmd.args[1])
+    if err != nil || keep < 1 {
+      return fmt.Errorf("invalid number of episodes to keep: %s", cmd.args[1])
+    }
+  }
+
+  t := time.Now().UTC()
+
+  err = s.db.EnableFeedDownloads(
+    context.Background(),
+    database.EnableFeedDownloadsParams{
+      FeedID:       feedID,
+      CreatedAt:    t,
+      KeepEpisodes: int32(keep),
+    },
+  )
+  if err != nil {
+    return fmt.Errorf("couldn't turn on downloads: %w", err)
+  }
+
+  queued, err := s.db.QueueDownloads(context.Background(), t)
+  if err != nil {
+    return fmt.Errorf("couldn't queue downloads: %w", err)
+  }
+
+  fmt.Printf("Keeping the latest %d episodes of %s, %d downloads queued\n", keep, feedURL, queued)
+  fmt.Println("agg downloads them as it runs, or run: downloads run")
+  return nil
+}
+
+// disableDownloads turns off downloading a feed and drops whatever of it is
+// still queued or half downloaded. A download in progress notices the next
+// time it saves its progress and stops.
+func disableDownloads(s *state, feedID uuid.UUID, feedURL string) error {
+
+  tx, err := s.conn.BeginTx(context.Background(), nil)
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  qtx := s.db.WithTx(tx)
+
+  removed, err := qtx.DisableFeedDownloads(context.Background(), feedID)
+  if err != nil {
+    return fmt.Errorf("couldn't turn off downloads: %w", err)
+  }
+  if removed == 0 {
+    return fmt.Errorf("%s isn't being downloaded", feedURL)
+  }
+
+  paths, err := qtx.CancelFeedDownloads(context.Background(), feedID)
+  if err != nil {
+    return fmt.Errorf("couldn't cancel queued downloads: %w", err)
+  }
+
+  err = tx.Commit()
+  if err != nil {
+    return err
+  }
+
+  for _, path := range paths {
+    if !path.Valid {
+      continue
+    }
+    err = os.Remove(path.String + ".part")
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+      log.Printf("Error removing %s: %v", path.String+".part", err)
+    }
+  }
+
+  fmt.Printf("Stopped downloading %s, %d unfinished downloads cancelled, episodes already downloaded are kept\n", feedURL, len(paths))
+  return nil
+}
+
+// removeDownloadFiles deletes the files, finished or partial, of downloads
+// whose rows are gone.
+func removeDownloadFiles(paths []sql.NullString) {
+  for _, path := range paths {
+    if !path.Valid {
+      continue
+    }
+    for _, name := range []string{path.String, path.String + ".part"} {
+      err := os.Remove(name)
+      if err != nil && !errors.Is(err, os.ErrNotExist) {
+        log.Printf("Error removing %s: %v", name, err)
+      }
+    }
+  }
+}
+
+// handlerDownloads lists the downloads of the feeds the user follows.
+// "downloads run" works through everyone's queue, as agg does, since the
+// download directory is shared.
+func handlerDownloads(s *state, cmd command, user database.User) error {
+
+  if len(cmd.args) > 0 && cmd.args[0] == "run" {
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    done, failed := runDownloads(ctx, s)
+    fmt.Printf("%d downloads finished, %d failed\n", done, failed)
+    return nil
+  }
+
+  feeds, err := s.db.ListFeedDownloads(context.Background(), user.ID)
+  if err != nil {
+    return fmt.Errorf("couldn't get download settings: %w", err)
+  }
+
+  if len(feeds) == 0 {
+    return fmt.Errorf("none of your feeds are being downloaded, turn one on with: download <feed url>")
+  }
+
+  for _, feed := range feeds {
+    fmt.Printf("Feed: %s, URL: %s, keeping %d episodes\n", feed.FeedName, feed.Url, feed.KeepEpisodes)
+  }
+
+  downloads, err := s.db.ListDownloads(context.Background(), user.ID)
+  if err != nil {
+    return fmt.Errorf("couldn't get downloads: %w", err)
+  }
+
+  fmt.Printf("%d downloads:\n", len(downloads))
+  for _, download := range downloads {
+    progress := formatSize(download.BytesDownloaded)
+    if download.TotalBytes.Valid {
+      progress = fmt.Sprintf("%s of %s (%d%%)", progress, formatSize(download.TotalBytes.Int64), download.BytesDownloaded*100/download.TotalBytes.Int64)
+    }
+
+    fmt.Printf("[%s] %s from %s\n", download.Status, download.Title, download.FeedName)
+    switch download.Status {
+    case "done":
+      fmt.Printf("    %s, saved to %s\n", progress, download.Path.String)
+    case "queued", "downloading":
+      if download.BytesDownloaded > 0 {
+        fmt.Printf("    %s\n", progress)
+      }
+    }
+    if download.LastError.Valid && download.Status != "done" {
+      fmt.Printf("    Last error: %s\n", download.LastError.String)
+    }
+  }
+  return nil
+}
+
+// downloadClaimLease is how long a claimed download stays reserved. It is
+// renewed as data arrives, so it only runs out if the process dies.
+const downloadClaimLease = 10 * time.Minute
+
+// downloadProgressEvery is how often a running download saves its progress
+// and renews its lease.
+const downloadProgressEvery = 5 * time.Second
+
+// maxDownloadAttempts is how many times a download is tried before it is
+// given up on.
+const maxDownloadAttempts = 3
+
+var (
+  errDownloadTooLarge = errors.New("enclosure exceeds maximum download size")
+  errDownloadStalled  = errors.New("download stalled")
+  // errDownloadCancelled means the download's row is gone because its feed
+  // stopped being downloaded.
+  errDownloadCancelled = errors.New("download cancelled")
+)
+
+// runDownloads queues the latest episodes of every feed with downloads
+// turned on, downloads the queue one enclosure at a time until it is empty
+// or ctx is cancelled, then prunes episodes beyond each feed's retention.
+// It returns how many downloads finished and how many failed.
+func runDownloads(ctx context.Context, s *state) (int, int) {
+
+  done, failed := 0, 0
+
+  queued, err := s.db.QueueDownloads(ctx, time.Now().UTC())
+  if err != nil {
+    if ctx.Err() == nil {
+      log.Printf("Error queueing downloads %v", err)
+    }
+    return done, failed
+  }
+  if queued > 0 {
+    log.Printf("Queued %d new downloads", queued)
+  }
+
+  for ctx.Err() == nil {
+    now := time.Now().UTC()
+
+    download, err := s.db.ClaimDownload(
+      ctx,
+      database.ClaimDownloadParams{
+        LeaseUntil: sql.NullTime{
+          Time:  now.Add(downloadClaimLease),
+          Valid: true,
+        },
+        Now: now,
+      },
+    )
+    if errors.Is(err, sql.ErrNoRows) {
+      break
+    }
+    if err != nil {
+      if ctx.Err() == nil {
+        log.Printf("Error claiming download %v", err)
+      }
+      break
+    }
+
+    err = downloadEnclosure(ctx, s, download)
+    if err == nil {
+      done++
+      continue
+    }
+    if errors.Is(err, errDownloadCancelled) {
+      log.Printf("Download %s cancelled, its feed is no longer downloaded", download.ID)
+      continue
+    }
+
+    if recordDownloadFailure(ctx, s, download, err) {
+      failed++
+    }
+  }
+
+  pruneDownloads(ctx, s)
+  return done, failed
+}
+
+// recordDownloadFailure puts a download back in the queue to be retried
+// later, or marks it failed once it runs out of attempts or can never
+// succeed. It reports whether the download was given up on.
+func recordDownloadFailure(ctx context.Context, s *state, download database.Download, downloadErr error) bool {
+
+  // Whatever stopped us, the download has to be handed back.
+  writeCtx := context.WithoutCancel(ctx)
+
+  params := database.RecordDownloadFailureParams{
+    ID:     download.ID,
+    Status: "queued",
+    LastError: sql.NullString{
+      String: downloadErr.Error(),
+      Valid: true,
+    },
+  }
+
+  switch {
+  case ctx.Err() != nil:
+    // Interrupted by shutdown; the partial file is resumed next time.
+    params.LastError.String = "interrupted, will resume"
+  case errors.Is(downloadErr, errDownloadTooLarge) || int(download.Attempts) >= maxDownloadAttempts:
+    params.Status = "failed"
+  default:
+    params.ClaimedUntil = sql.NullTime{
+      Time:  time.Now().UTC().Add(time.Duration(1<<download.Attempts) * time.Minute),
+      Valid: true,
+    }
+  }
+
+  err := s.db.RecordDownloadFailure(writeCtx, params)
+  if err != nil {
+    log.Printf("Error recording download failure %v", err)
+  }
+
+  if params.Status == "failed" {
+    log.Printf("Download failed: %v", downloadErr)
+    return true
+  }
+  if ctx.Err() == nil {
+    log.Printf("Download will be retried: %v", downloadErr)
+  }
+  return false
+}
+
+// downloadEnclosure fetches one enclosure into a .part file next to its
+// final path, resuming with a range request when an earlier attempt left
+// one behind, and renames it into place once it is complete.
+func downloadEnclosure(ctx context.Context, s *state, download database.Download) error {
+
+  source, err := s.db.GetDownloadSource(ctx, download.EnclosureID)
+  if err != nil {
+    return fmt.Errorf("couldn't look up enclosure: %w", err)
+  }
+
+  maxBytes := s.cfg.MaxDownloadBytes()
+  if source.Length.Valid && source.Length.Int64 > maxBytes {
+    return fmt.Errorf("%w: %s is %s", errDownloadTooLarge, source.Url, formatSize(source.Length.Int64))
+  }
+
+  filePath := download.Path.String
+  if !download.Path.Valid {
+    dir, err := s.cfg.DownloadDirectory()
+    if err != nil {
+      return err
+    }
+    filePath = filepath.Join(dir, safeFileName(source.FeedName, "feed"), downloadFileName(source, download.ID))
+  }
+
+  err = os.MkdirAll(filepath.Dir(filePath), 0755)
+  if err != nil {
+    return err
+  }
+
+  partPath := filePath + ".part"
+  var offset int64
+  if info, err := os.Stat(partPath); err == nil {
+    offset = info.Size()
+  }
+
+  ctx, cancel := context.WithCancelCause(ctx)
+  defer cancel(nil)
+
+  // A connection that stops delivering data is dropped rather than left
+  // hanging; the next attempt resumes from where it got to.
+  stall := time.AfterFunc(s.client.readTimeout, func() {
+    cancel(errDownloadStalled)
+  })
+  defer stall.Stop()
+
+  req, err := http.NewRequestWithContext(ctx, "GET", source.Url, nil)
+  if err != nil {
+    return err
+  }
+
+  req.Header.Set("User-Agent", s.client.userAgent)
+  if offset > 0 {
+    req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+  }
+
+  rsp, err := s.client.download.Do(req)
+  if err != nil {
+    return downloadError(ctx, err)
+  }
+  defer rsp.Body.Close()
+
+  total := int64(-1)
+  switch rsp.StatusCode {
+  case http.StatusPartialContent:
+    start, size, ok := parseContentRange(rsp.Header.Get("Content-Range"))
+    if !ok || start != offset {
+      os.Remove(partPath)
+      return fmt.Errorf("server resumed %s at the wrong offset, starting over", source.Url)
+    }
+    total = size
+  case http.StatusOK:
+    // The server ignored the range, so start from the beginning.
+    offset = 0
+    total = rsp.ContentLength
+  case http.StatusRequestedRangeNotSatisfiable:
+    os.Remove(partPath)
+    return fmt.Errorf("couldn't resume %s, starting over", source.Url)
+  default:
+    return fmt.Errorf("unexpected status code: %s", rsp.Status)
+  }
+
+  if total > maxBytes {
+    os.Remove(partPath)
+    return fmt.Errorf("%w: %s is %s", errDownloadTooLarge, source.Url, formatSize(total))
+  }
+
+  flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+  if offset > 0 {
+    flags = os.O_WRONLY | os.O_APPEND
+  }
+
+  file, err := os.OpenFile(partPath, flags, 0644)
+  if err != nil {
+    return err
+  }
+  defer file.Close()
+
+  written := offset
+  saveProgress := func() {
+    saved, err := s.db.SetDownloadProgress(
+      ctx,
+      database.SetDownloadProgressParams{
+        ID: download.ID,
+        Path: sql.NullString{
+          String: filePath,
+          Valid: true,
+        },
+        BytesDownloaded: written,
+        TotalBytes: sql.NullInt64{
+          Int64: total,
+          Valid: total > 0,
+        },
+        ClaimedUntil: sql.NullTime{
+          Time:  time.Now().UTC().Add(downloadClaimLease),
+          Valid: true,
+        },
+      },
+    )
+    if err != nil && ctx.Err() == nil {
+      log.Printf("Error saving download progress %v", err)
+    }
+    if err == nil && saved == 0 {
+      cancel(errDownloadCancelled)
+    }
+  }
+
+  saveProgress()
+  lastSaved := time.Now()
+  buf := make([]byte, 32*1024)
+
+  for {
+    n, readErr := rsp.Body.Read(buf)
+    if n > 0 {
+      stall.Reset(s.client.readTimeout)
+
+      if written+int64(n) > maxBytes {
+        file.Close()
+        os.Remove(partPath)
+        return fmt.Errorf("%w: %s is over %s", errDownloadTooLarge, source.Url, formatSize(maxBytes))
+      }
+
+      _, err = file.Write(buf[:n])
+      if err != nil {
+        return err
+      }
+      written += int64(n)
+
+      if time.Since(lastSaved) >= downloadProgressEvery {
+        saveProgress()
+        lastSaved = time.Now()
+      }
+    }
+
+    if readErr == io.EOF {
+      break
+    }
+    if readErr != nil {
+      err = downloadError(ctx, readErr)
+      if errors.Is(err, errDownloadCancelled) {
+        file.Close()
+        os.Remove(partPath)
+      }
+      return err
+    }
+  }
+
+  if total > 0 && written != total {
+    return fmt.Errorf("download of %s ended after %d of %d bytes", source.Url, written, total)
+  }
+
+  err = file.Close()
+  if err != nil {
+    return err
+  }
+
+  err = os.Rename(partPath, filePath)
+  if err != nil {
+    return err
+  }
+
+  completed, err := s.db.CompleteDownload(
+    ctx,
+    database.CompleteDownloadParams{
+      ID:              download.ID,
+      BytesDownloaded: written,
+    },
+  )
+  if err != nil {
+    return fmt.Errorf("couldn't mark download complete: %w", err)
+  }
+  if completed == 0 {
+    os.Remove(filePath)
+    return errDownloadCancelled
+  }
+
+  log.Printf("Downloaded %s from %s to %s", source.Title, source.FeedName, filePath)
+  return nil
+}
+
+// downloadError reports a stall or cancellation as such rather than as the
+// context cancellation it was implemented with.
+func downloadError(ctx context.Context, err error) error {
+  cause := context.Cause(ctx)
+  if errors.Is(cause, errDownloadStalled) || errors.Is(cause, errDownloadCancelled) {
+    return cause
+  }
+  return err
+}
+
+// pruneDownloads deletes downloaded episodes that have fallen outside their
+// feed's retention.
+func pruneDownloads(ctx context.Context, s *state) {
+
+  expired, err := s.db.GetExpiredDownloads(ctx)
+  if err != nil {
+    if ctx.Err() == nil {
+      log.Printf("Error finding expired downloads %v", err)
+    }
+    return
+  }
+
+  for _, download := range expired {
+    if download.Path.Valid {
+      err = os.Remove(download.Path.String)
+      if err != nil && !errors.Is(err, os.ErrNotExist) {
+        log.Printf("Error removing %s: %v", download.Path.String, err)
+        continue
+      }
+    }
+
+    err = s.db.MarkDownloadRemoved(ctx, download.ID)
+    if err != nil {
+      log.Printf("Error marking download removed %v", err)
+    }
+  }
+}
+
+// parseContentRange reads "bytes start-end/size" from a 206 response. size
+// is -1 when the server gives it as "*".
+func parseContentRange(value string) (int64, int64, bool) {
+
+  value, ok := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+  if !ok {
+    return 0, 0, false
+  }
+
+  span, size, ok := strings.Cut(value, "/")
+  if !ok {
+    return 0, 0, false
+  }
+
+  first, _, ok := strings.Cut(span, "-")
+  if !ok {
+    return 0, 0, false
+  }
+
+  start, err := strconv.ParseInt(first, 10, 64)
+  if err != nil {
+    return 0, 0, false
+  }
+
+  if size == "*" {
+    return start, -1, true
+  }
+
+  total, err := strconv.ParseInt(size, 10, 64)
+  if err != nil {
+    return 0, 0, false
+  }
+  return start, total, true
+}
+
+// downloadFileName names an episode after its post title, with part of the
+// download ID so episodes with the same title don't collide. The extension
+// comes from the enclosure URL, or its MIME type if the URL has none.
+func downloadFileName(source database.GetDownloadSourceRow, id uuid.UUID) string {
+
+  ext := ""
+  if parsed, err := url.Parse(source.Url); err == nil {
+    ext = strings.ToLower(path.Ext(parsed.Path))
+  }
+
+  if len(ext) < 2 || len(ext) > 6 || strings.Trim(ext[1:], "abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+    ext = ""
+    if exts, err := mime.ExtensionsByType(source.MimeType.String); err == nil && len(exts) > 0 {
+      ext = exts[0]
+    }
+  }
+
+  return safeFileName(source.Title, "episode") + "-" + id.String()[:8] + ext
+}
+
+// safeFileName turns a title into something usable as a file name on any
+// platform, or returns fallback if nothing is left of it.
+func safeFileName(name, fallback string) string {
+
+  var b strings.Builder
+  dash := false
+
+  for _, r := range name {
+    if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+      b.WriteRune(r)
+      dash = false
+    } else if !dash && b.Len() > 0 {
+      b.WriteRune('-')
+      dash = true
+    }
+
+    if b.Len() >= 80 {
+      break
+    }
+  }
+
+  safe := strings.Trim(b.String(), "-")
+  if safe == "" {
+    return fallback
+  }
+  return safe
+}
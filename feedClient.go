@@ -0,0 +1,158 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/config"
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "os"
+    "time"
+)
+
+var errFeedTooLarge = errors.New("feed exceeds maximum size")
+
+// maxRedirects matches the limit net/http applies by default.
+const maxRedirects = 10
+
+type redirectTraceKey struct{}
+
+// redirectTrace records, for one fetch, where its redirects led and whether
+// all of them were permanent (301 or 308).
+type redirectTrace struct {
+  permanent bool
+  location  string
+}
+
+func traceRedirect(req *http.Request, via []*http.Request) error {
+
+  if len(via) >= maxRedirects {
+    return fmt.Errorf("stopped after %d redirects", maxRedirects)
+  }
+
+  trace, ok := req.Context().Value(redirectTraceKey{}).(*redirectTrace)
+  if !ok {
+    return nil
+  }
+
+  switch req.Response.StatusCode {
+  case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+    trace.location = req.URL.String()
+  default:
+    trace.permanent = false
+  }
+
+  return nil
+}
+
+// feedClient is the HTTP client shared by every feed fetch, configured from
+// .gatorconfig.json. Each feed request gets readTimeout once the host
+// limiter lets it through. download shares the limiter and robots.txt cache
+// but has no overall timeout, since an episode can take far longer than a
+// feed; a download instead fails once it stalls for readTimeout.
+type feedClient struct {
+  http        *http.Client
+  download    *http.Client
+  userAgent   string
+  maxBytes    int64
+  readTimeout time.Duration
+}
+
+func newFeedClient(cfg *config.Config) (*feedClient, error) {
+
+  connectTimeout, readTimeout := cfg.HTTPTimeouts()
+
+  proxy := http.ProxyFromEnvironment
+  if cfg.HTTPProxy != "" {
+    proxyURL, err := url.Parse(cfg.HTTPProxy)
+    if err != nil {
+      return nil, fmt.Errorf("invalid http_proxy: %w", err)
+    }
+    proxy = http.ProxyURL(proxyURL)
+  }
+
+  tlsConfig := &tls.Config{}
+  if cfg.HTTPCABundle != "" {
+    pem, err := os.ReadFile(cfg.HTTPCABundle)
+    if err != nil {
+      return nil, fmt.Errorf("couldn't read http_ca_bundle: %w", err)
+    }
+
+    pool, err := x509.SystemCertPool()
+    if err != nil {
+      pool = x509.NewCertPool()
+    }
+    if !pool.AppendCertsFromPEM(pem) {
+      return nil, fmt.Errorf("no certificates found in %s", cfg.HTTPCABundle)
+    }
+    tlsConfig.RootCAs = pool
+  }
+
+  base := &http.Transport{
+    Proxy: proxy,
+    DialContext: (&net.Dialer{
+      Timeout: connectTimeout,
+    }).DialContext,
+    TLSClientConfig:     tlsConfig,
+    TLSHandshakeTimeout: connectTimeout,
+    ForceAttemptHTTP2:   true,
+    MaxIdleConns:        100,
+    IdleConnTimeout:     90 * time.Second,
+  }
+
+  perMinute, burst := cfg.HostRateLimit()
+  limiter := newHostLimiter(perMinute, burst, cfg.HostDelay())
+
+  var robots *robotsCache
+  if cfg.RespectRobotsTxt {
+    robots = newRobotsCache(
+      &politeTransport{
+        base:    base,
+        limiter: limiter,
+        timeout: readTimeout,
+      },
+      cfg.UserAgent(),
+    )
+  }
+
+  return &feedClient{
+    http: &http.Client{
+      Transport: &politeTransport{
+        base:    base,
+        limiter: limiter,
+        robots:  robots,
+        timeout: readTimeout,
+      },
+      CheckRedirect: traceRedirect,
+    },
+    download: &http.Client{
+      Transport: &politeTransport{
+        base:    base,
+        limiter: limiter,
+        robots:  robots,
+      },
+    },
+    userAgent:   cfg.UserAgent(),
+    maxBytes:    cfg.MaxFeedBytes(),
+    readTimeout: readTimeout,
+  }, nil
+}
+
+// readBody reads at most maxBytes, failing rather than truncating when the
+// body is larger.
+func (c *feedClient) readBody(body io.Reader) ([]byte, error) {
+
+  data, err := io.ReadAll(io.LimitReader(body, c.maxBytes+1))
+  if err != nil {
+    return nil, err
+  }
+
+  if int64(len(data)) > c.maxBytes {
+    return nil, fmt.Errorf("%w of %d bytes", errFeedTooLarge, c.maxBytes)
+  }
+  return data, nil
+}
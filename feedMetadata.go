@@ -0,0 +1,108 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+    "github.com/google/uuid"
+)
+
+// updateFeedMetadata stores what a feed says about itself, including its
+// refresh hints. servedFrom is the URL its body came from, after any
+// redirects. Everything is overwritten, so a feed that drops its image or
+// language loses it here too.
+func updateFeedMetadata(ctx context.Context, s *state, feedID uuid.UUID, servedFrom string, feed *RSSFeed) error {
+
+  // Atom icons in particular are often relative to the feed.
+  image := feedImage(feed)
+  if base, err := url.Parse(servedFrom); err == nil && image != "" {
+    if resolved, err := base.Parse(image); err == nil {
+      image = resolved.String()
+    }
+  }
+
+  optional := func(value string) sql.NullString {
+    value = strings.TrimSpace(value)
+    return sql.NullString{
+      String: value,
+      Valid:  value != "",
+    }
+  }
+
+  hints := feedRefreshHints(feed)
+
+  err := s.db.UpdateFeedMetadata(
+    ctx,
+    database.UpdateFeedMetadataParams{
+      ID:              feedID,
+      Title:           optional(feed.Channel.Title),
+      Description:     optional(feed.Channel.Description),
+      SiteLink:        optional(feed.Channel.Link),
+      ImageUrl:        optional(image),
+      Language:        optional(feed.Channel.Language),
+      Generator:       optional(feed.Channel.Generator),
+      Ttl:             hints.TTL,
+      UpdatePeriod:    hints.UpdatePeriod,
+      UpdateFrequency: hints.UpdateFrequency,
+      SkipHours:       hints.SkipHours,
+      SkipDays:        hints.SkipDays,
+    },
+  )
+  if err != nil {
+    return fmt.Errorf("couldn't save feed metadata: %w", err)
+  }
+  return nil
+}
+
+func handlerFeed(s *state, cmd command) error {
+  if len(cmd.args) != 2 || cmd.args[0] != "info" {
+    return fmt.Errorf("usage: feed info <url>")
+  }
+
+  feed, err := s.db.GetFeedInfo(context.Background(), canonicalURL(cmd.args[1]))
+  if errors.Is(err, sql.ErrNoRows) {
+    return fmt.Errorf("no feed with url %s", cmd.args[1])
+  }
+  if err != nil {
+    return fmt.Errorf("Error getting feed: %w", err)
+  }
+
+  show := func(label string, value sql.NullString) {
+    if value.Valid {
+      fmt.Printf("%-13s%s\n", label+":", value.String)
+    }
+  }
+
+  fmt.Printf("%-13s%s\n", "Feed:", feed.Name)
+  fmt.Printf("%-13s%s\n", "URL:", feed.Url)
+  show("Title", feed.Title)
+  show("Description", feed.Description)
+  show("Site", feed.SiteLink)
+  show("Image", feed.ImageUrl)
+  show("Language", feed.Language)
+  show("Generator", feed.Generator)
+  fmt.Printf("%-13s%s, %d followers\n", "Added by:", feed.CreatedBy, feed.Followers)
+
+  if feed.LastFetchedAt.Valid {
+    fmt.Printf("%-13s%s\n", "Last fetched:", feed.LastFetchedAt.Time.Format(time.RFC1123))
+  } else {
+    fmt.Printf("%-13s%s\n", "Last fetched:", "never")
+  }
+
+  if feed.DisabledAt.Valid {
+    fmt.Printf("%-13s%s: %s\n", "Disabled:", feed.DisabledAt.Time.Format(time.RFC1123), feed.LastError.String)
+  }
+  return nil
+}
+
+func orUnknown(value sql.NullString) string {
+  if !value.Valid {
+    return "unknown"
+  }
+  return value.String
+}
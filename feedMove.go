@@ -0,0 +1,339 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "log"
+    "net/url"
+    "sync"
+    "time"
+    "github.com/google/uuid"
+)
+
+// moveFeed points a feed at newURL, records why in feed_history and lets its
+// followers know. feeds.canonical_url
+// is unique, so if another feed already has newURL the two are merged into
+// that one: follows, posts and history move over and this feed is deleted.
+// It returns the ID of the feed that now owns newURL.
+func moveFeed(ctx context.Context, s *state, feed database.Feed, newURL, reason string) (uuid.UUID, error) {
+
+  tx, err := s.conn.BeginTx(ctx, nil)
+  if err != nil {
+    return uuid.Nil, err
+  }
+  defer tx.Rollback()
+
+  qtx := s.db.WithTx(tx)
+  var mergedPaths []sql.NullString
+
+  targetID, err := qtx.GetFeedByUrl(ctx, canonicalURL(newURL))
+  switch {
+  case errors.Is(err, sql.ErrNoRows) || (err == nil && targetID == feed.ID):
+    // Either no feed has the new URL or it is this feed's own, spelled
+    // differently (say https instead of http), which is still worth
+    // fetching from.
+    targetID = feed.ID
+    err = qtx.UpdateFeedURL(
+      ctx,
+      database.UpdateFeedURLParams{
+        ID:           feed.ID,
+        Url:          newURL,
+        CanonicalUrl: canonicalURL(newURL),
+      },
+    )
+    if err != nil {
+      return uuid.Nil, fmt.Errorf("couldn't update feed url: %w", err)
+    }
+  case err != nil:
+    return uuid.Nil, err
+  default:
+    mergedPaths, err = mergeFeed(ctx, qtx, feed.ID, targetID)
+    if err != nil {
+      return uuid.Nil, err
+    }
+    reason = "merged into existing feed: " + reason
+  }
+
+  err = qtx.CreateFeedHistory(
+    ctx,
+    database.CreateFeedHistoryParams{
+      ID:        uuid.New(),
+      FeedID:    targetID,
+      CreatedAt: time.Now().UTC(),
+      OldUrl:    feed.Url,
+      NewUrl:    newURL,
+      Reason:    reason,
+    },
+  )
+  if err != nil {
+    return uuid.Nil, fmt.Errorf("couldn't record feed history: %w", err)
+  }
+
+  err = qtx.NotifyFeedFollowers(
+    ctx,
+    database.NotifyFeedFollowersParams{
+      FeedID:  targetID,
+      Message: fmt.Sprintf("Feed %s has moved to %s (%s)", feed.Url, newURL, reason),
+    },
+  )
+  if err != nil {
+    return uuid.Nil, fmt.Errorf("couldn't notify followers: %w", err)
+  }
+
+  err = tx.Commit()
+  if err != nil {
+    return uuid.Nil, err
+  }
+
+  removeDownloadFiles(mergedPaths)
+
+  log.Printf("Feed %s moved to %s (%s)", feed.Url, newURL, reason)
+  return targetID, nil
+}
+
+// mergeFeed moves everything of fromID's worth keeping to toID and deletes
+// it. It returns the files of the downloads that went with it, for the
+// caller to remove once the transaction commits.
+func mergeFeed(ctx context.Context, qtx *database.Queries, fromID, toID uuid.UUID) ([]sql.NullString, error) {
+
+  err := qtx.MoveFeedFollows(
+    ctx,
+    database.MoveFeedFollowsParams{
+      ToFeedID:   toID,
+      FromFeedID: fromID,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't move follows: %w", err)
+  }
+
+  err = qtx.MoveFeedPosts(
+    ctx,
+    database.MoveFeedPostsParams{
+      ToFeedID:   toID,
+      FromFeedID: fromID,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't move posts: %w", err)
+  }
+
+  err = qtx.MoveFeedHistory(
+    ctx,
+    database.MoveFeedHistoryParams{
+      ToFeedID:   toID,
+      FromFeedID: fromID,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't move feed history: %w", err)
+  }
+
+  err = qtx.MoveFeedDownloads(
+    ctx,
+    database.MoveFeedDownloadsParams{
+      ToFeedID:   toID,
+      FromFeedID: fromID,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't move download settings: %w", err)
+  }
+
+  // Whatever is left duplicates a post the other feed already has. Fold
+  // each into its twin first, as migration 021 does: enclosures the twin
+  // lacks move across with their downloads, a download moves to the twin's
+  // copy of an enclosure that has none, and revisions move over.
+  err = qtx.FoldFeedPostEnclosures(
+    ctx,
+    database.FoldFeedPostEnclosuresParams{
+      ToFeedID:   toID,
+      FromFeedID: fromID,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't move enclosures of duplicate posts: %w", err)
+  }
+
+  err = qtx.FoldFeedPostDownloads(
+    ctx,
+    database.FoldFeedPostDownloadsParams{
+      ToFeedID:   toID,
+      FromFeedID: fromID,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't move downloads of duplicate posts: %w", err)
+  }
+
+  err = qtx.FoldFeedPostRevisions(
+    ctx,
+    database.FoldFeedPostRevisionsParams{
+      ToFeedID:   toID,
+      FromFeedID: fromID,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't move revisions of duplicate posts: %w", err)
+  }
+
+  // Only downloads of enclosures both posts had downloaded are left.
+  paths, err := qtx.GetFeedPostDownloads(ctx, fromID)
+  if err != nil {
+    return nil, fmt.Errorf("couldn't get downloads of duplicate posts: %w", err)
+  }
+
+  err = qtx.DeleteFeedPosts(ctx, fromID)
+  if err != nil {
+    return nil, fmt.Errorf("couldn't delete duplicate posts: %w", err)
+  }
+
+  // Follows of the old row go with it.
+  err = qtx.DeleteFeed(ctx, fromID)
+  if err != nil {
+    return nil, fmt.Errorf("couldn't delete merged feed: %w", err)
+  }
+  return paths, nil
+}
+
+// movedFeedURL decides whether a successful fetch says the feed has moved,
+// through permanent redirects, an announced replacement feed or a changed
+// <atom:link rel="self">, and returns the new URL with the reason.
+func movedFeedURL(ctx context.Context, s *state, feedURL string, result fetchResult) (string, string) {
+
+  if result.MovedTo != "" {
+    return result.MovedTo, "permanent redirect"
+  }
+
+  if result.Feed == nil {
+    return "", ""
+  }
+
+  // Publishers often leave new-feed-url pointing at the feed itself.
+  if announced := announcedFeedURL(result.Feed); announced != "" && canonicalURL(announced) != canonicalURL(feedURL) {
+    if verifiedMove(ctx, s, feedURL, announced) {
+      return announced, "feed announced a new URL"
+    }
+  }
+
+  if self := selfLink(result.Feed); self != "" && canonicalURL(self) != canonicalURL(feedURL) {
+    if verifiedMove(ctx, s, feedURL, self) {
+      return self, "feed self link changed"
+    }
+  }
+
+  return "", ""
+}
+
+// rejectedMoveRecheck is how long a URL a feed claims to have moved to is
+// ignored after it failed to serve a feed.
+const rejectedMoveRecheck = 24 * time.Hour
+
+// rejectedMoves remembers, per feed URL and candidate, when a claimed new URL
+// last failed verification, so a stale self link costs one extra fetch a day
+// rather than one every poll.
+var rejectedMoves = struct {
+  sync.Mutex
+  at map[string]time.Time
+}{at: make(map[string]time.Time)}
+
+// verifiedMove reports whether candidate, a URL the feed itself claims to
+// live at, is worth moving to: an http(s) URL that serves a feed without
+// redirecting back to feedURL. Both self links and announced URLs are often
+// stale, mistyped or worse, and a move can't be undone once followers are
+// told. One that leads back would flip the feed between the two URLs on
+// every poll.
+func verifiedMove(ctx context.Context, s *state, feedURL, candidate string) bool {
+
+  current, err := url.Parse(feedURL)
+  if err != nil {
+    return false
+  }
+  parsed, err := url.Parse(candidate)
+  if err != nil || !parsed.IsAbs() || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+    return false
+  }
+  // Plenty of feeds declare an http self link while being served over https.
+  if current.Scheme == "https" && parsed.Scheme == "http" {
+    return false
+  }
+
+  key := feedURL + " " + candidate
+
+  rejectedMoves.Lock()
+  rejectedAt, rejected := rejectedMoves.at[key]
+  rejectedMoves.Unlock()
+
+  if rejected && time.Since(rejectedAt) < rejectedMoveRecheck {
+    return false
+  }
+
+  result, err := fetchFeed(ctx, s.client, candidate, "", "")
+  if err == nil && redirectsBack(result, feedURL) {
+    err = fmt.Errorf("%s redirects back to %s", candidate, feedURL)
+  }
+
+  rejectedMoves.Lock()
+  defer rejectedMoves.Unlock()
+
+  if err != nil {
+    // Being cancelled says nothing about the candidate.
+    if ctx.Err() == nil {
+      rejectedMoves.at[key] = time.Now()
+    }
+    return false
+  }
+
+  delete(rejectedMoves.at, key)
+  return true
+}
+
+// redirectsBack reports whether a fetch ended up at, or was permanently
+// redirected to, feedURL.
+func redirectsBack(result fetchResult, feedURL string) bool {
+  current := canonicalURL(feedURL)
+  if canonicalURL(result.FinalURL) == current {
+    return true
+  }
+  return result.MovedTo != "" && canonicalURL(result.MovedTo) == current
+}
+
+// markFeedGone disables a feed whose server says it no longer exists and
+// tells its followers.
+func markFeedGone(ctx context.Context, s *state, feed database.Feed, fetchErr error) {
+
+  err := s.db.MarkFeedGone(
+    ctx,
+    database.MarkFeedGoneParams{
+      ID: feed.ID,
+      Now: sql.NullTime{
+        Time:  time.Now().UTC(),
+        Valid: true,
+      },
+      LastError: sql.NullString{
+        String: fetchErr.Error(),
+        Valid: true,
+      },
+    },
+  )
+  if err != nil {
+    log.Printf("Error marking feed %s as gone: %v", feed.Url, err)
+    return
+  }
+
+  err = s.db.NotifyFeedFollowers(
+    ctx,
+    database.NotifyFeedFollowersParams{
+      FeedID:  feed.ID,
+      Message: fmt.Sprintf("Feed %s (%s) has been retired by its publisher and will no longer be updated", feed.Name, feed.Url),
+    },
+  )
+  if err != nil {
+    log.Printf("Error notifying followers of feed %s: %v", feed.Url, err)
+  }
+
+  log.Printf("Feed %s is gone, disabled it", feed.Url)
+}
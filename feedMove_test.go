@@ -0,0 +1,106 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/config"
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// newTestClient is a feedClient with the host limiter out of the way.
+func newTestClient(t *testing.T) *feedClient {
+
+  client, err := newFeedClient(&config.Config{
+    HostRequestsPerMinute: 60000,
+    HostBurst:             100,
+    HostMinDelay:          "0s",
+  })
+  if err != nil {
+    t.Fatalf("newFeedClient: %v", err)
+  }
+  return client
+}
+
+func TestVerifiedMove(t *testing.T) {
+
+  mux := http.NewServeMux()
+  serveFeed := func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/rss+xml")
+    w.Write([]byte(sampleRSS))
+  }
+  mux.HandleFunc("/feed.xml", serveFeed)
+  mux.HandleFunc("/elsewhere.xml", serveFeed)
+  mux.Handle("/back.xml", http.RedirectHandler("/feed.xml", http.StatusMovedPermanently))
+  mux.Handle("/back-for-now.xml", http.RedirectHandler("/feed.xml/", http.StatusFound))
+  mux.Handle("/feed.xml/", http.RedirectHandler("/feed.xml", http.StatusFound))
+  mux.HandleFunc("/missing.xml", http.NotFound)
+
+  server := httptest.NewServer(mux)
+  defer server.Close()
+
+  s := &state{client: newTestClient(t)}
+  feedURL := server.URL + "/feed.xml"
+
+  tests := []struct {
+    candidate string
+    want      bool
+  }{
+    {server.URL + "/elsewhere.xml", true},
+    {server.URL + "/back.xml", false},
+    {server.URL + "/back-for-now.xml", false},
+    {server.URL + "/missing.xml", false},
+    {"ftp://example.com/feed.xml", false},
+    {"/relative.xml", false},
+  }
+
+  for _, test := range tests {
+    got := verifiedMove(context.Background(), s, feedURL, test.candidate)
+    if got != test.want {
+      t.Errorf("verifiedMove(%q) = %v; want %v", test.candidate, got, test.want)
+    }
+  }
+}
+
+func TestMovedFeedURLSameCanonicalURL(t *testing.T) {
+
+  feed := &RSSFeed{}
+  feed.Channel.AtomLinks = []atomLink{{Href: "https://www.example.com/feed/", Rel: "self"}}
+
+  // No client: a self link that is only spelled differently must not be
+  // fetched at all.
+  newURL, reason := movedFeedURL(context.Background(), &state{}, "https://example.com/feed", fetchResult{Feed: feed})
+  if newURL != "" {
+    t.Errorf("movedFeedURL = %q (%s); want no move", newURL, reason)
+  }
+}
+
+func TestMovedFeedURLAnnounced(t *testing.T) {
+
+  mux := http.NewServeMux()
+  mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/rss+xml")
+    w.Write([]byte(sampleRSS))
+  })
+  mux.Handle("/new.xml", http.RedirectHandler("/feed.xml", http.StatusMovedPermanently))
+
+  server := httptest.NewServer(mux)
+  defer server.Close()
+
+  feedURL := server.URL + "/feed.xml"
+
+  // An announced URL that only leads back to the feed is no move.
+  feed := &RSSFeed{}
+  feed.Channel.NewFeedURL = server.URL + "/new.xml"
+  newURL, reason := movedFeedURL(context.Background(), &state{client: newTestClient(t)}, feedURL, fetchResult{Feed: feed})
+  if newURL != "" {
+    t.Errorf("movedFeedURL = %q (%s); want no move", newURL, reason)
+  }
+
+  // Nor is one naming the feed itself, which needs no fetch to tell.
+  feed.Channel.NewFeedURL = "https://www.example.com/feed/"
+  newURL, reason = movedFeedURL(context.Background(), &state{}, "https://example.com/feed", fetchResult{Feed: feed})
+  if newURL != "" {
+    t.Errorf("movedFeedURL = %q (%s); want no move", newURL, reason)
+  }
+}
@@ -1,64 +1,172 @@
 package main
 
 import (
+    "bytes"
     "encoding/xml"
     "context"
     "net/http"
-    "io"
     "errors"
+    "fmt"
     "html"
+    "strings"
+    "time"
 )
 
 type RSSFeed struct {
   Channel struct {
       Title       string `xml:"title"`
+      // AtomLinks must come before Link so <atom:link> doesn't land in Link.
+      AtomLinks   []atomLink `xml:"http://www.w3.org/2005/Atom link"`
       Link        string `xml:"link"`
       Description string `xml:"description"`
       Item        []RSSItem `xml:"item"`
 
+      // Both <image> and <itunes:image> end up here.
+      Images    []rssImage `xml:"image"`
+      Language  string     `xml:"language"`
+      Generator string     `xml:"generator"`
+
+      TTL             string   `xml:"ttl"`
+      SkipHours       []string `xml:"skipHours>hour"`
+      SkipDays        []string `xml:"skipDays>day"`
+      UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+      UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+
+      // Publishers retiring a feed announce its replacement with one of these.
+      NewFeedURL       string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd new-feed-url"`
+      RedirectLocation string `xml:"redirect>newLocation"`
+
     }`xml:"channel"`
 }
 
+// rssImage is an RSS <image>, which has the URL in a child element, or an
+// <itunes:image>, which has it in href.
+type rssImage struct {
+  URL  string `xml:"url"`
+  Href string `xml:"href,attr"`
+}
+
+// errFeedGone means the server answered 410: the feed has been retired.
+var errFeedGone = errors.New("feed is gone (410)")
+
 type RSSItem struct {
   Title       string `xml:"title"`
   Link        string `xml:"link"`
   Description string `xml:"description"`
   PubDate     string `xml:"pubDate"`
+  GUID        string `xml:"guid"`
+  // Content is the full article where the feed provides one; Description is
+  // often just a teaser.
+  Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+
+  // RSS puts an email, often followed by the name in parentheses, in
+  // <author>; many feeds use <dc:creator> for the plain name instead.
+  Authors    []string       `xml:"author"`
+  Creators   []string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+  Categories []string       `xml:"category"`
+  Enclosures []RSSEnclosure `xml:"enclosure"`
+}
+
+type RSSEnclosure struct {
+  URL    string `xml:"url,attr"`
+  Type   string `xml:"type,attr"`
+  Length string `xml:"length,attr"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// fetchResult is what a fetch produced: the parsed feed, unless the server
+// answered 304, plus the validators to send on the next request, how long
+// the server said the response stays fresh and, if every redirect on the way
+// was permanent, the URL the feed now lives at.
+type fetchResult struct {
+  Feed         *RSSFeed
+  ETag         string
+  LastModified string
+  NotModified  bool
+  FreshUntil   time.Time
+  MovedTo      string
+  // FinalURL is where the response came from once redirects were followed.
+  FinalURL     string
+}
+
+func fetchFeed(ctx context.Context, client *feedClient, feedURL, etag, lastModified string) (fetchResult, error) {
+
+  trace := &redirectTrace{permanent: true}
+  ctx = context.WithValue(ctx, redirectTraceKey{}, trace)
 
   req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 
   if err != nil {
-    return nil, err
+    return fetchResult{}, err
   }
 
-  client := http.Client{}
-  req.Header.Set("User-Agent", "gator")
-  rsp, err := client.Do(req)
+  req.Header.Set("User-Agent", client.userAgent)
+  if etag != "" {
+    req.Header.Set("If-None-Match", etag)
+  }
+  if lastModified != "" {
+    req.Header.Set("If-Modified-Since", lastModified)
+  }
+  rsp, err := client.http.Do(req)
   if err != nil {
-    return nil, err
+    return fetchResult{}, err
   }
 
   defer rsp.Body.Close()
 
+  result := fetchResult{
+    ETag:         rsp.Header.Get("ETag"),
+    LastModified: rsp.Header.Get("Last-Modified"),
+    FreshUntil:   freshUntil(rsp.Header, time.Now()),
+    FinalURL:     rsp.Request.URL.String(),
+  }
+
+  if trace.permanent && trace.location != "" && trace.location != feedURL {
+    result.MovedTo = trace.location
+  }
+
+  if rsp.StatusCode == http.StatusNotModified {
+    // A 304 may leave the validators out, in which case the old ones still hold.
+    if result.ETag == "" {
+      result.ETag = etag
+    }
+    if result.LastModified == "" {
+      result.LastModified = lastModified
+    }
+    result.NotModified = true
+    return result, nil
+  }
+
+  if rsp.StatusCode == http.StatusGone {
+    return fetchResult{}, errFeedGone
+  }
+
   if rsp.StatusCode != http.StatusOK {
-    return nil, errors.New("unexpected status code")
+    return fetchResult{}, fmt.Errorf("unexpected status code: %s", rsp.Status)
   }
 
-  body, err := io.ReadAll(rsp.Body)
+  body, err := client.readBody(rsp.Body)
   if err != nil {
-    return nil, err
+    return fetchResult{}, err
   } 
 
-  var rssFeed RSSFeed
+  body, err = toUTF8(body, rsp.Header.Get("Content-Type"))
+  if err != nil {
+    return fetchResult{}, err
+  }
 
-  err = xml.Unmarshal(body, &rssFeed)
+  rssFeed, err := parseFeed(body, rsp.Header.Get("Content-Type"))
   if err != nil {
-    return nil, err
+    return fetchResult{}, err
   }
   
+  unescapeFeed(rssFeed)
+  result.Feed = rssFeed
+  return result, nil
+}
+
+// unescapeFeed decodes the entities left in titles and descriptions by
+// feeds that escape their text twice.
+func unescapeFeed(rssFeed *RSSFeed) {
   rssFeed.Channel.Title = html.UnescapeString(rssFeed.Channel.Title)
   rssFeed.Channel.Description = html.UnescapeString(rssFeed.Channel.Description)
 
@@ -66,5 +174,138 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
     rssFeed.Channel.Item[i].Title = html.UnescapeString(rssFeed.Channel.Item[i].Title)
     rssFeed.Channel.Item[i].Description = html.UnescapeString(rssFeed.Channel.Item[i].Description)
   }
-  return &rssFeed, nil
+}
+
+// parseFeed looks at the content type and root element of the document and
+// decodes it with the matching format, always handing back the RSSFeed shape
+// scrapeFeeds uses.
+func parseFeed(body []byte, contentType string) (*RSSFeed, error) {
+
+  if isJSONFeed(contentType, body) {
+    return parseJSONFeed(body)
+  }
+
+  root, err := rootElement(body)
+  if err != nil {
+    return nil, err
+  }
+
+  switch root.Local {
+  case "rss":
+    var rssFeed RSSFeed
+    err = unmarshalXML(body, &rssFeed)
+    if err != nil {
+      return nil, err
+    }
+    return &rssFeed, nil
+  case "feed":
+    return parseAtom(body)
+  case "RDF":
+    return parseRDF(body)
+  }
+
+  return nil, fmt.Errorf("unsupported feed format: <%s>", root.Local)
+}
+
+func rootElement(body []byte) (xml.Name, error) {
+
+  decoder := xml.NewDecoder(bytes.NewReader(body))
+  decoder.CharsetReader = charsetReader
+
+  for {
+    token, err := decoder.Token()
+    if err != nil {
+      return xml.Name{}, fmt.Errorf("couldn't find root element: %w", err)
+    }
+
+    if start, ok := token.(xml.StartElement); ok {
+      return start.Name, nil
+    }
+  }
+}
+
+// selfLink returns the feed's own URL as it declares it with
+// <atom:link rel="self">, if it does.
+func selfLink(feed *RSSFeed) string {
+  for _, link := range feed.Channel.AtomLinks {
+    if link.Rel == "self" {
+      return strings.TrimSpace(link.Href)
+    }
+  }
+  return ""
+}
+
+// announcedFeedURL returns the replacement URL a retired feed points to via
+// <itunes:new-feed-url> or <redirect><newLocation>, if it has one.
+func announcedFeedURL(feed *RSSFeed) string {
+  if newURL := strings.TrimSpace(feed.Channel.NewFeedURL); newURL != "" {
+    return newURL
+  }
+  return strings.TrimSpace(feed.Channel.RedirectLocation)
+}
+
+// feedImage returns the channel's image or, for podcasts that only have
+// artwork, its <itunes:image>.
+func feedImage(feed *RSSFeed) string {
+  for _, image := range feed.Channel.Images {
+    if url := strings.TrimSpace(image.URL); url != "" {
+      return url
+    }
+  }
+  for _, image := range feed.Channel.Images {
+    if href := strings.TrimSpace(image.Href); href != "" {
+      return href
+    }
+  }
+  return ""
+}
+
+// itemID is what identifies an item within its feed: the guid / Atom id /
+// JSON Feed id when there is one, otherwise its link, and as a last resort
+// its title. URLs are canonicalized, so a permalink that gains tracking
+// parameters or switches to https is still the same post.
+func itemID(item RSSItem) string {
+  if guid := strings.TrimSpace(item.GUID); guid != "" {
+    return canonicalURL(guid)
+  }
+  if link := strings.TrimSpace(item.Link); link != "" {
+    return canonicalURL(link)
+  }
+  return strings.TrimSpace(item.Title)
+}
+
+// itemAuthors returns the item's author names with duplicates and blanks
+// dropped, reducing "jane@example.com (Jane Doe)" to "Jane Doe".
+func itemAuthors(item RSSItem) []string {
+  var names []string
+  for _, author := range append(item.Authors, item.Creators...) {
+    author = strings.TrimSpace(author)
+    if open := strings.LastIndex(author, "("); open > 0 && strings.HasSuffix(author, ")") {
+      author = strings.TrimSpace(author[open+1 : len(author)-1])
+    }
+    names = appendUnique(names, author)
+  }
+  return names
+}
+
+// itemCategories returns the item's categories with duplicates and blanks
+// dropped.
+func itemCategories(item RSSItem) []string {
+  var names []string
+  for _, category := range item.Categories {
+    names = appendUnique(names, strings.TrimSpace(category))
+  }
+  return names
+}
+
+func appendUnique(values []string, value string) []string {
+  if value == "" {
+    return values
+  }
+  for _, existing := range values {
+    if strings.EqualFold(existing, value) {
+      return values
+    }
+  }
+  return append(values, value)
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+const sampleRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+  xmlns:atom="http://www.w3.org/2005/Atom"
+  xmlns:content="http://purl.org/rss/1.0/modules/content/"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"
+  xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Example Show</title>
+    <atom:link href="https://example.com/rss" rel="self" type="application/rss+xml"/>
+    <link>https://example.com/</link>
+    <description>A show about examples</description>
+    <language>en-gb</language>
+    <ttl>60</ttl>
+    <image><url>https://example.com/cover.jpg</url></image>
+    <itunes:new-feed-url>https://feeds.example.net/show</itunes:new-feed-url>
+    <item>
+      <title>Pilot</title>
+      <link>https://example.com/pilot</link>
+      <description>Teaser</description>
+      <content:encoded><![CDATA[<p>Full <em>show</em> notes</p>]]></content:encoded>
+      <pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate>
+      <guid isPermaLink="false">pilot-1</guid>
+      <author>ada@example.com (Ada)</author>
+      <dc:creator>Grace</dc:creator>
+      <category>Science</category>
+      <category>Tech</category>
+      <enclosure url="https://example.com/pilot.mp3" type="audio/mpeg" length="4321"/>
+    </item>
+  </channel>
+</rss>`
+
+func TestParseRSS(t *testing.T) {
+
+  feed, err := parseFeed([]byte(sampleRSS), "application/rss+xml")
+  if err != nil {
+    t.Fatalf("parseFeed: %v", err)
+  }
+
+  channel := feed.Channel
+  if channel.Title != "Example Show" || channel.Link != "https://example.com/" {
+    t.Errorf("title, link = %q, %q; the atom:link must not land in link", channel.Title, channel.Link)
+  }
+  if channel.Description != "A show about examples" || channel.Language != "en-gb" || channel.TTL != "60" {
+    t.Errorf("description, language, ttl = %q, %q, %q", channel.Description, channel.Language, channel.TTL)
+  }
+  if selfLink(feed) != "https://example.com/rss" {
+    t.Errorf("selfLink = %q", selfLink(feed))
+  }
+  if announcedFeedURL(feed) != "https://feeds.example.net/show" {
+    t.Errorf("announcedFeedURL = %q", announcedFeedURL(feed))
+  }
+
+  if len(channel.Item) != 1 {
+    t.Fatalf("got %d items; want 1", len(channel.Item))
+  }
+
+  want := RSSItem{
+    Title:       "Pilot",
+    Link:        "https://example.com/pilot",
+    Description: "Teaser",
+    PubDate:     "Tue, 02 Jan 2024 10:00:00 GMT",
+    GUID:        "pilot-1",
+    Content:     "<p>Full <em>show</em> notes</p>",
+    Authors:     []string{"ada@example.com (Ada)"},
+    Creators:    []string{"Grace"},
+    Categories:  []string{"Science", "Tech"},
+    Enclosures: []RSSEnclosure{
+      {URL: "https://example.com/pilot.mp3", Type: "audio/mpeg", Length: "4321"},
+    },
+  }
+  if !reflect.DeepEqual(channel.Item[0], want) {
+    t.Errorf("item = %+v\nwant %+v", channel.Item[0], want)
+  }
+}
+
+func TestParseFeedUnsupported(t *testing.T) {
+
+  _, err := parseFeed([]byte(`<html><body>not a feed</body></html>`), "text/html")
+  if err == nil {
+    t.Errorf("parseFeed should reject an HTML page")
+  }
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+    "context"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// hostLimiter spaces out requests per hostname with a token bucket plus a
+// minimum delay between consecutive requests, so a concurrent agg doesn't
+// hammer a host that serves many of our feeds.
+type hostLimiter struct {
+  mu       sync.Mutex
+  perSec   float64
+  burst    float64
+  minDelay time.Duration
+  hosts    map[string]*hostBucket
+}
+
+type hostBucket struct {
+  tokens      float64
+  refilledAt  time.Time
+  lastRequest time.Time
+}
+
+func newHostLimiter(perMinute float64, burst int, minDelay time.Duration) *hostLimiter {
+  return &hostLimiter{
+    perSec:   perMinute / 60,
+    burst:    float64(burst),
+    minDelay: minDelay,
+    hosts:    make(map[string]*hostBucket),
+  }
+}
+
+// reserve books the next request slot for host and returns how long the
+// caller has to wait for it.
+func (l *hostLimiter) reserve(host string, now time.Time) time.Duration {
+
+  l.mu.Lock()
+  defer l.mu.Unlock()
+
+  bucket, ok := l.hosts[host]
+  if !ok {
+    bucket = &hostBucket{tokens: l.burst, refilledAt: now}
+    l.hosts[host] = bucket
+  }
+
+  bucket.tokens += now.Sub(bucket.refilledAt).Seconds() * l.perSec
+  if bucket.tokens > l.burst {
+    bucket.tokens = l.burst
+  }
+  bucket.refilledAt = now
+
+  at := now
+  if bucket.tokens < 1 {
+    at = now.Add(time.Duration((1 - bucket.tokens) / l.perSec * float64(time.Second)))
+  }
+  // Tokens may go negative: that is the debt later callers wait out.
+  bucket.tokens--
+
+  if earliest := bucket.lastRequest.Add(l.minDelay); at.Before(earliest) {
+    at = earliest
+  }
+  bucket.lastRequest = at
+
+  return at.Sub(now)
+}
+
+// Wait blocks until a request to host is allowed or ctx is done.
+func (l *hostLimiter) Wait(ctx context.Context, host string) error {
+
+  wait := l.reserve(strings.ToLower(host), time.Now())
+  if wait <= 0 {
+    return nil
+  }
+
+  timer := time.NewTimer(wait)
+  defer timer.Stop()
+
+  select {
+  case <-timer.C:
+    return nil
+  case <-ctx.Done():
+    return ctx.Err()
+  }
+}
+
+// politeTransport applies the host limiter, and robots.txt when enabled, to
+// every request including redirects. timeout, when set, bounds each request
+// from the moment the limiter lets it through until its body is closed, so
+// time spent queueing behind other feeds on the same host doesn't count
+// against it.
+type politeTransport struct {
+  base    http.RoundTripper
+  limiter *hostLimiter
+  robots  *robotsCache
+  timeout time.Duration
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+  if t.robots != nil {
+    err := t.robots.check(req.Context(), req.URL)
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  err := t.limiter.Wait(req.Context(), req.URL.Hostname())
+  if err != nil {
+    return nil, err
+  }
+
+  if t.timeout <= 0 {
+    return t.base.RoundTrip(req)
+  }
+
+  ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+  rsp, err := t.base.RoundTrip(req.WithContext(ctx))
+  if err != nil {
+    cancel()
+    return nil, err
+  }
+
+  rsp.Body = &cancelOnClose{ReadCloser: rsp.Body, cancel: cancel}
+  return rsp, nil
+}
+
+// cancelOnClose releases a request's timeout once its body is done with.
+type cancelOnClose struct {
+  io.ReadCloser
+  cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+  err := b.ReadCloser.Close()
+  b.cancel()
+  return err
+}
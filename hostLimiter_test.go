@@ -0,0 +1,76 @@
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+func TestHostLimiterReserve(t *testing.T) {
+
+  // One request a second with a burst of two.
+  limiter := newHostLimiter(60, 2, 0)
+  now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+  tests := []struct {
+    host  string
+    after time.Duration
+    want  time.Duration
+  }{
+    {"example.com", 0, 0},
+    {"example.com", 0, 0},
+    // The burst is spent, so each further request waits its turn.
+    {"example.com", 0, time.Second},
+    {"example.com", 0, 2 * time.Second},
+    // Other hosts have buckets of their own.
+    {"example.org", 0, 0},
+    // Time pays off the debt before it refills the bucket.
+    {"example.com", 2500 * time.Millisecond, 500 * time.Millisecond},
+  }
+
+  for i, test := range tests {
+    got := limiter.reserve(test.host, now.Add(test.after))
+    if got != test.want {
+      t.Errorf("reserve %d for %s = %v; want %v", i, test.host, got, test.want)
+    }
+  }
+}
+
+func TestHostLimiterMinDelay(t *testing.T) {
+
+  limiter := newHostLimiter(6000, 10, 500*time.Millisecond)
+  now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+  waits := []time.Duration{
+    limiter.reserve("example.com", now),
+    limiter.reserve("example.com", now),
+    limiter.reserve("example.com", now.Add(2*time.Second)),
+  }
+  want := []time.Duration{0, 500 * time.Millisecond, 0}
+
+  for i := range want {
+    if waits[i] != want[i] {
+      t.Errorf("reserve %d = %v; want %v", i, waits[i], want[i])
+    }
+  }
+}
+
+func TestHostLimiterWait(t *testing.T) {
+
+  limiter := newHostLimiter(1, 1, 0)
+
+  err := limiter.Wait(context.Background(), "Example.com")
+  if err != nil {
+    t.Fatalf("first Wait = %v; want no wait", err)
+  }
+
+  // Hosts are case-insensitive, so this one shares the spent bucket and
+  // would wait a minute.
+  ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+  defer cancel()
+
+  err = limiter.Wait(ctx, "EXAMPLE.COM")
+  if err != context.DeadlineExceeded {
+    t.Errorf("second Wait = %v; want %v", err, context.DeadlineExceeded)
+  }
+}
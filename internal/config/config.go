@@ -5,12 +5,60 @@ import (
     "os"
     "encoding/json"
     "path/filepath"
+    "time"
 )
 
 
+// defaultMaxFeedFailures is used when max_feed_failures is not set.
+const defaultMaxFeedFailures = 10
+
+// Polling bounds used when min_poll_interval / max_poll_interval are unset.
+const (
+  defaultMinPollInterval = time.Minute
+  defaultMaxPollInterval = 24 * time.Hour
+)
+
+// HTTP client settings used when the http_* settings are unset.
+const (
+  defaultConnectTimeout = 10 * time.Second
+  defaultReadTimeout    = 30 * time.Second
+  defaultMaxFeedBytes   = 10 << 20
+  defaultUserAgent      = "gator"
+)
+
+// Per-host politeness used when the host_* settings are unset.
+const (
+  defaultHostRequestsPerMinute = 30
+  defaultHostBurst             = 2
+  defaultHostMinDelay          = time.Second
+)
+
+// Podcast download settings used when the download_* settings are unset.
+const (
+  defaultDownloadDir      = "gator-downloads"
+  defaultDownloadMaxBytes = 1 << 30
+  defaultDownloadKeep     = 5
+)
+
 type Config struct {
   DBurl string `json:"db_url"`
   CurrentUserName string `json:"current_user_name"`
+  MaxFeedFailures int `json:"max_feed_failures,omitempty"`
+  MinPollInterval string `json:"min_poll_interval,omitempty"`
+  MaxPollInterval string `json:"max_poll_interval,omitempty"`
+  HostRequestsPerMinute float64 `json:"host_requests_per_minute,omitempty"`
+  HostBurst int `json:"host_burst,omitempty"`
+  HostMinDelay string `json:"host_min_delay,omitempty"`
+  RespectRobotsTxt bool `json:"respect_robots_txt,omitempty"`
+  HTTPConnectTimeout string `json:"http_connect_timeout,omitempty"`
+  HTTPReadTimeout string `json:"http_read_timeout,omitempty"`
+  HTTPProxy string `json:"http_proxy,omitempty"`
+  HTTPMaxFeedBytes int64 `json:"http_max_feed_bytes,omitempty"`
+  HTTPCABundle string `json:"http_ca_bundle,omitempty"`
+  HTTPUserAgent string `json:"http_user_agent,omitempty"`
+  DownloadDir string `json:"download_dir,omitempty"`
+  DownloadMaxBytes int64 `json:"download_max_bytes,omitempty"`
+  DownloadKeep int `json:"download_keep,omitempty"`
 }
 
 func getConfigFilePath() (string, error) {
@@ -80,3 +128,128 @@ func (cfg *Config) SetUser(setName string) error {
   return nil
 }
 
+// FeedFailureLimit is how many fetches in a row may fail before a feed is
+// disabled.
+func (cfg *Config) FeedFailureLimit() int {
+  if cfg.MaxFeedFailures <= 0 {
+    return defaultMaxFeedFailures
+  }
+  return cfg.MaxFeedFailures
+}
+
+// PollIntervalBounds returns how soon and how late a feed may be polled
+// again, whatever the feed itself asks for.
+func (cfg *Config) PollIntervalBounds() (time.Duration, time.Duration) {
+
+  minInterval, err := time.ParseDuration(cfg.MinPollInterval)
+  if err != nil || minInterval <= 0 {
+    minInterval = defaultMinPollInterval
+  }
+
+  maxInterval, err := time.ParseDuration(cfg.MaxPollInterval)
+  if err != nil || maxInterval <= 0 {
+    maxInterval = defaultMaxPollInterval
+  }
+
+  if maxInterval < minInterval {
+    maxInterval = minInterval
+  }
+
+  return minInterval, maxInterval
+}
+
+// HostRateLimit returns the token bucket for each feed host: how many
+// requests per minute it refills at and how many it can burst.
+func (cfg *Config) HostRateLimit() (float64, int) {
+
+  perMinute := cfg.HostRequestsPerMinute
+  if perMinute <= 0 {
+    perMinute = defaultHostRequestsPerMinute
+  }
+
+  burst := cfg.HostBurst
+  if burst <= 0 {
+    burst = defaultHostBurst
+  }
+
+  return perMinute, burst
+}
+
+// HostDelay is the least time between two requests to the same host.
+func (cfg *Config) HostDelay() time.Duration {
+
+  delay, err := time.ParseDuration(cfg.HostMinDelay)
+  if err != nil || delay < 0 {
+    return defaultHostMinDelay
+  }
+  return delay
+}
+
+// HTTPTimeouts returns how long fetches may take to connect and to read the
+// whole response.
+func (cfg *Config) HTTPTimeouts() (time.Duration, time.Duration) {
+
+  connect, err := time.ParseDuration(cfg.HTTPConnectTimeout)
+  if err != nil || connect <= 0 {
+    connect = defaultConnectTimeout
+  }
+
+  read, err := time.ParseDuration(cfg.HTTPReadTimeout)
+  if err != nil || read <= 0 {
+    read = defaultReadTimeout
+  }
+
+  return connect, read
+}
+
+// MaxFeedBytes is the largest response body a fetch will read.
+func (cfg *Config) MaxFeedBytes() int64 {
+  if cfg.HTTPMaxFeedBytes <= 0 {
+    return defaultMaxFeedBytes
+  }
+  return cfg.HTTPMaxFeedBytes
+}
+
+// UserAgent is what fetches send as their User-Agent header.
+func (cfg *Config) UserAgent() string {
+  if cfg.HTTPUserAgent == "" {
+    return defaultUserAgent
+  }
+  return cfg.HTTPUserAgent
+}
+
+// DownloadDirectory is where enclosures are saved, one subdirectory per
+// feed. A relative download_dir is taken from the home directory.
+func (cfg *Config) DownloadDirectory() (string, error) {
+
+  dir := cfg.DownloadDir
+  if dir == "" {
+    dir = defaultDownloadDir
+  }
+  if filepath.IsAbs(dir) {
+    return dir, nil
+  }
+
+  homeDir, err := os.UserHomeDir()
+  if err != nil {
+    return "", err
+  }
+  return filepath.Join(homeDir, dir), nil
+}
+
+// MaxDownloadBytes is the largest enclosure that will be downloaded.
+func (cfg *Config) MaxDownloadBytes() int64 {
+  if cfg.DownloadMaxBytes <= 0 {
+    return defaultDownloadMaxBytes
+  }
+  return cfg.DownloadMaxBytes
+}
+
+// DownloadRetention is how many of a feed's latest episodes are kept when
+// download doesn't say.
+func (cfg *Config) DownloadRetention() int {
+  if cfg.DownloadKeep <= 0 {
+    return defaultDownloadKeep
+  }
+  return cfg.DownloadKeep
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeedFollows = `-- name: CreateFeedFollows :one
@@ -73,7 +74,7 @@ func (q *Queries) CreateFeedFollows(ctx context.Context, arg CreateFeedFollowsPa
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 
-SELECT feed_follows.id, feed_follows.user_id, feed_id, feed_follows.created_at, feed_follows.updated_at, users.id, users.created_at, users.updated_at, users.name, feeds.id, feeds.user_id, feeds.created_at, feeds.updated_at, feeds.name, url, last_fetched_at 
+SELECT feed_follows.id, feed_follows.user_id, feed_id, feed_follows.created_at, feed_follows.updated_at, users.id, users.created_at, users.updated_at, users.name, feeds.id, feeds.user_id, feeds.created_at, feeds.updated_at, feeds.name, url, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_success_at, disabled_at, next_fetch_at, post_interval_seconds, description, site_link, title, image_url, language, generator, canonical_url, ttl, update_period, update_frequency, skip_hours, skip_days 
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -81,22 +82,43 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID            uuid.UUID
-	UserID        uuid.UUID
-	FeedID        uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	ID_2          uuid.UUID
-	CreatedAt_2   time.Time
-	UpdatedAt_2   time.Time
-	Name          string
-	ID_3          uuid.UUID
-	UserID_2      uuid.UUID
-	CreatedAt_3   time.Time
-	UpdatedAt_3   time.Time
-	Name_2        string
-	Url           string
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	UserID              uuid.UUID
+	FeedID              uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	ID_2                uuid.UUID
+	CreatedAt_2         time.Time
+	UpdatedAt_2         time.Time
+	Name                string
+	ID_3                uuid.UUID
+	UserID_2            uuid.UUID
+	CreatedAt_3         time.Time
+	UpdatedAt_3         time.Time
+	Name_2              string
+	Url                 string
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	ClaimedUntil        sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastSuccessAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	NextFetchAt         sql.NullTime
+	PostIntervalSeconds sql.NullInt64
+	Description         sql.NullString
+	SiteLink            sql.NullString
+	Title               sql.NullString
+	ImageUrl            sql.NullString
+	Language            sql.NullString
+	Generator           sql.NullString
+	CanonicalUrl        string
+	Ttl                 string
+	UpdatePeriod        string
+	UpdateFrequency     string
+	SkipHours           []string
+	SkipDays            []string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -125,6 +147,27 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.Name_2,
 			&i.Url,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.NextFetchAt,
+			&i.PostIntervalSeconds,
+			&i.Description,
+			&i.SiteLink,
+			&i.Title,
+			&i.ImageUrl,
+			&i.Language,
+			&i.Generator,
+			&i.CanonicalUrl,
+			&i.Ttl,
+			&i.UpdatePeriod,
+			&i.UpdateFrequency,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
 		); err != nil {
 			return nil, err
 		}
@@ -7,18 +7,100 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
 SELECT id FROM feeds
-WHERE url = $1
+WHERE canonical_url = $1
 `
 
-func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (uuid.UUID, error) {
-	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
+func (q *Queries) GetFeedByUrl(ctx context.Context, canonicalUrl string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByUrl, canonicalUrl)
 	var id uuid.UUID
 	err := row.Scan(&id)
 	return id, err
 }
+
+const getFeedInfo = `-- name: GetFeedInfo :one
+SELECT feeds.id, feeds.user_id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.last_fetched_at, feeds.etag, feeds.last_modified, feeds.claimed_until, feeds.consecutive_failures, feeds.last_error, feeds.last_success_at, feeds.disabled_at, feeds.next_fetch_at, feeds.post_interval_seconds, feeds.description, feeds.site_link, feeds.title, feeds.image_url, feeds.language, feeds.generator, feeds.canonical_url, feeds.ttl, feeds.update_period, feeds.update_frequency, feeds.skip_hours, feeds.skip_days, users.name AS created_by,
+  (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS followers
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.canonical_url = $1
+`
+
+type GetFeedInfoRow struct {
+	ID                  uuid.UUID
+	UserID              uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	ClaimedUntil        sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastSuccessAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	NextFetchAt         sql.NullTime
+	PostIntervalSeconds sql.NullInt64
+	Description         sql.NullString
+	SiteLink            sql.NullString
+	Title               sql.NullString
+	ImageUrl            sql.NullString
+	Language            sql.NullString
+	Generator           sql.NullString
+	CanonicalUrl        string
+	Ttl                 string
+	UpdatePeriod        string
+	UpdateFrequency     string
+	SkipHours           []string
+	SkipDays            []string
+	CreatedBy           string
+	Followers           int64
+}
+
+func (q *Queries) GetFeedInfo(ctx context.Context, canonicalUrl string) (GetFeedInfoRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedInfo, canonicalUrl)
+	var i GetFeedInfoRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.PostIntervalSeconds,
+		&i.Description,
+		&i.SiteLink,
+		&i.Title,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Generator,
+		&i.CanonicalUrl,
+		&i.Ttl,
+		&i.UpdatePeriod,
+		&i.UpdateFrequency,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.CreatedBy,
+		&i.Followers,
+	)
+	return i, err
+}
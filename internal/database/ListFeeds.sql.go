@@ -7,18 +7,25 @@ package database
 
 import (
 	"context"
+	"database/sql"
 )
 
 const listFeeds = `-- name: ListFeeds :many
-SELECT feeds.name as feed_name, feeds.url, users.name
+SELECT feeds.name as feed_name, feeds.url, users.name, feeds.consecutive_failures, feeds.last_error, feeds.disabled_at, feeds.title, feeds.site_link, feeds.language
 FROM feeds
 JOIN users ON feeds.user_id = users.id
 `
 
 type ListFeedsRow struct {
-	FeedName string
-	Url      string
-	Name     string
+	FeedName            string
+	Url                 string
+	Name                string
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	DisabledAt          sql.NullTime
+	Title               sql.NullString
+	SiteLink            sql.NullString
+	Language            sql.NullString
 }
 
 func (q *Queries) ListFeeds(ctx context.Context) ([]ListFeedsRow, error) {
@@ -30,7 +37,17 @@ func (q *Queries) ListFeeds(ctx context.Context) ([]ListFeedsRow, error) {
 	var items []ListFeedsRow
 	for rows.Next() {
 		var i ListFeedsRow
-		if err := rows.Scan(&i.FeedName, &i.Url, &i.Name); err != nil {
+		if err := rows.Scan(
+			&i.FeedName,
+			&i.Url,
+			&i.Name,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.DisabledAt,
+			&i.Title,
+			&i.SiteLink,
+			&i.Language,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
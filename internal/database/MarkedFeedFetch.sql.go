@@ -7,18 +7,127 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, user_id, created_at, updated_at, name, url, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC Nulls FIRST
-LIMIT 1
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET claimed_until = $1
+WHERE id IN (
+  SELECT due.id FROM feeds AS due
+  WHERE (due.claimed_until IS NULL OR due.claimed_until < $2)
+  AND due.disabled_at IS NULL
+  AND (due.next_fetch_at IS NULL OR due.next_fetch_at <= $2)
+  AND (
+    due.consecutive_failures = 0
+    OR due.last_fetched_at IS NULL
+    -- 2^11 minutes is already past the cap; a larger exponent could overflow.
+    OR due.last_fetched_at + LEAST(POWER(2, LEAST(due.consecutive_failures, 11)) * INTERVAL '1 minute', INTERVAL '1 day') < $2
+  )
+  ORDER BY due.next_fetch_at ASC Nulls FIRST, due.last_fetched_at ASC Nulls FIRST
+  LIMIT $3
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_success_at, disabled_at, next_fetch_at, post_interval_seconds, description, site_link, title, image_url, language, generator, canonical_url, ttl, update_period, update_frequency, skip_hours, skip_days
+`
+
+type ClaimFeedsToFetchParams struct {
+	LeaseUntil sql.NullTime
+	Now        sql.NullTime
+	BatchSize  int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.LeaseUntil, arg.Now, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.NextFetchAt,
+			&i.PostIntervalSeconds,
+			&i.Description,
+			&i.SiteLink,
+			&i.Title,
+			&i.ImageUrl,
+			&i.Language,
+			&i.Generator,
+			&i.CanonicalUrl,
+			&i.Ttl,
+			&i.UpdatePeriod,
+			&i.UpdateFrequency,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedGone = `-- name: MarkFeedGone :exec
+UPDATE feeds
+SET disabled_at = $1,
+last_error = $2
+WHERE id = $3
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+type MarkFeedGoneParams struct {
+	Now       sql.NullTime
+	LastError sql.NullString
+	ID        uuid.UUID
+}
+
+func (q *Queries) MarkFeedGone(ctx context.Context, arg MarkFeedGoneParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedGone, arg.Now, arg.LastError, arg.ID)
+	return err
+}
+
+const markedFeedFetch = `-- name: MarkedFeedFetch :one
+UPDATE feeds
+SET last_fetched_at = $1,
+updated_at = $1,
+claimed_until = NULL
+WHERE id = $2
+RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_success_at, disabled_at, next_fetch_at, post_interval_seconds, description, site_link, title, image_url, language, generator, canonical_url, ttl, update_period, update_frequency, skip_hours, skip_days
+`
+
+type MarkedFeedFetchParams struct {
+	Now sql.NullTime
+	ID  uuid.UUID
+}
+
+// now comes from Go, like the now ClaimFeedsToFetch compares it with: NOW()
+// is in the session's time zone, which needn't be UTC.
+func (q *Queries) MarkedFeedFetch(ctx context.Context, arg MarkedFeedFetchParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, markedFeedFetch, arg.Now, arg.ID)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -28,20 +137,57 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.PostIntervalSeconds,
+		&i.Description,
+		&i.SiteLink,
+		&i.Title,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Generator,
+		&i.CanonicalUrl,
+		&i.Ttl,
+		&i.UpdatePeriod,
+		&i.UpdateFrequency,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
 
-const markedFeedFetch = `-- name: MarkedFeedFetch :one
+const recordFeedFailure = `-- name: RecordFeedFailure :one
 UPDATE feeds
-SET last_fetched_at = NOW(),
-updated_at = NOW()
-WHERE id = $1
-RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at
+SET consecutive_failures = consecutive_failures + 1,
+last_error = $1,
+disabled_at = CASE
+  WHEN consecutive_failures + 1 >= $2::integer THEN $3
+  ELSE disabled_at
+END
+WHERE id = $4
+RETURNING id, user_id, created_at, updated_at, name, url, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_success_at, disabled_at, next_fetch_at, post_interval_seconds, description, site_link, title, image_url, language, generator, canonical_url, ttl, update_period, update_frequency, skip_hours, skip_days
 `
 
-func (q *Queries) MarkedFeedFetch(ctx context.Context, id uuid.UUID) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markedFeedFetch, id)
+type RecordFeedFailureParams struct {
+	LastError   sql.NullString
+	MaxFailures int32
+	Now         sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.LastError,
+		arg.MaxFailures,
+		arg.Now,
+		arg.ID,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -51,6 +197,90 @@ func (q *Queries) MarkedFeedFetch(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.Name,
 		&i.Url,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.PostIntervalSeconds,
+		&i.Description,
+		&i.SiteLink,
+		&i.Title,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Generator,
+		&i.CanonicalUrl,
+		&i.Ttl,
+		&i.UpdatePeriod,
+		&i.UpdateFrequency,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+last_error = NULL,
+last_success_at = $1
+WHERE id = $2
+`
+
+type RecordFeedSuccessParams struct {
+	Now sql.NullTime
+	ID  uuid.UUID
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.Now, arg.ID)
+	return err
+}
+
+const releaseFeedClaim = `-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedClaim, id)
+	return err
+}
+
+const setFeedCacheHeaders = `-- name: SetFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2,
+last_modified = $3
+WHERE id = $1
+`
+
+type SetFeedCacheHeadersParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheHeaders(ctx context.Context, arg SetFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheHeaders, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const setFeedNextFetch = `-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1
+`
+
+type SetFeedNextFetchParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, arg.ID, arg.NextFetchAt)
+	return err
+}
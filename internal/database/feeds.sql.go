@@ -7,31 +7,35 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds(id, user_id, name, created_at, updated_at, url)
+INSERT INTO feeds(id, user_id, name, created_at, updated_at, url, canonical_url)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
-Returning id, user_id, created_at, updated_at, name, url, last_fetched_at
+Returning id, user_id, created_at, updated_at, name, url, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_success_at, disabled_at, next_fetch_at, post_interval_seconds, description, site_link, title, image_url, language, generator, canonical_url, ttl, update_period, update_frequency, skip_hours, skip_days
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	Name      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Url       string
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Url          string
+	CanonicalUrl string
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -42,6 +46,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Url,
+		arg.CanonicalUrl,
 	)
 	var i Feed
 	err := row.Scan(
@@ -52,6 +57,76 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Name,
 		&i.Url,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.PostIntervalSeconds,
+		&i.Description,
+		&i.SiteLink,
+		&i.Title,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Generator,
+		&i.CanonicalUrl,
+		&i.Ttl,
+		&i.UpdatePeriod,
+		&i.UpdateFrequency,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2,
+description = $3,
+site_link = $4,
+image_url = $5,
+language = $6,
+generator = $7,
+ttl = $8,
+update_period = $9,
+update_frequency = $10,
+skip_hours = $11,
+skip_days = $12
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID              uuid.UUID
+	Title           sql.NullString
+	Description     sql.NullString
+	SiteLink        sql.NullString
+	ImageUrl        sql.NullString
+	Language        sql.NullString
+	Generator       sql.NullString
+	Ttl             string
+	UpdatePeriod    string
+	UpdateFrequency string
+	SkipHours       []string
+	SkipDays        []string
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.SiteLink,
+		arg.ImageUrl,
+		arg.Language,
+		arg.Generator,
+		arg.Ttl,
+		arg.UpdatePeriod,
+		arg.UpdateFrequency,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+	)
+	return err
+}
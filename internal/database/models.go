@@ -11,14 +11,56 @@ import (
 	"github.com/google/uuid"
 )
 
+type Download struct {
+	ID              uuid.UUID
+	EnclosureID     uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Status          string
+	Path            sql.NullString
+	BytesDownloaded int64
+	TotalBytes      sql.NullInt64
+	Attempts        int32
+	LastError       sql.NullString
+	ClaimedUntil    sql.NullTime
+	CompletedAt     sql.NullTime
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	UserID        uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	UserID              uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	ClaimedUntil        sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastSuccessAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	NextFetchAt         sql.NullTime
+	PostIntervalSeconds sql.NullInt64
+	Description         sql.NullString
+	SiteLink            sql.NullString
+	Title               sql.NullString
+	ImageUrl            sql.NullString
+	Language            sql.NullString
+	Generator           sql.NullString
+	CanonicalUrl        string
+	Ttl                 string
+	UpdatePeriod        string
+	UpdateFrequency     string
+	SkipHours           []string
+	SkipDays            []string
+}
+
+type FeedDownload struct {
+	FeedID       uuid.UUID
+	CreatedAt    time.Time
+	KeepEpisodes int32
 }
 
 type FeedFollow struct {
@@ -29,15 +71,65 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 }
 
+type FeedHistory struct {
+	ID        uuid.UUID
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	OldUrl    string
+	NewUrl    string
+	Reason    string
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Message   string
+	ReadAt    sql.NullTime
+}
+
 type Post struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         sql.NullString
+	PublishedAt         sql.NullTime
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Content             sql.NullString
+	Guid                string
+	ContentHash         string
+	CanonicalUrl        string
+}
+
+type PostAuthor struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostEnclosure struct {
+	ID       uuid.UUID
+	PostID   uuid.UUID
+	Url      string
+	MimeType sql.NullString
+	Length   sql.NullInt64
+}
+
+type PostRevision struct {
 	ID          uuid.UUID
+	PostID      uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
 	Title       string
-	Url         string
 	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	Content     sql.NullString
+	ContentHash string
 }
 
 type User struct {
@@ -13,78 +13,78 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const getPostID = `-- name: GetPostID :one
+SELECT id FROM posts
+WHERE feed_id = $1
+AND guid = $2
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+type GetPostIDParams struct {
+	FeedID uuid.UUID
+	Guid   string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Title,
-		&i.Url,
-		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
-	)
-	return i, err
+func (q *Queries) GetPostID(ctx context.Context, arg GetPostIDParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getPostID, arg.FeedID, arg.Guid)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-
-
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name from posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.published_at_inferred, posts.content, posts.guid, posts.content_hash, posts.canonical_url, feeds.name AS feed_name from posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 JOIN feeds  ON posts.feed_id = feeds.id
 WHERE feed_follows.user_id = $1
+AND ($2::text IS NULL OR EXISTS (
+  SELECT 1 FROM post_authors
+  WHERE post_authors.post_id = posts.id AND post_authors.name ILIKE $2
+))
+AND ($3::text IS NULL OR EXISTS (
+  SELECT 1 FROM post_categories
+  WHERE post_categories.post_id = posts.id AND post_categories.name ILIKE $3
+))
+AND (NOT $4::boolean OR EXISTS (
+  SELECT 1 FROM post_enclosures
+  WHERE post_enclosures.post_id = posts.id
+))
 ORDER BY posts.published_at DESC
-LIMIT $2
+LIMIT $5
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID         uuid.UUID
+	Author         sql.NullString
+	Category       sql.NullString
+	WithEnclosures bool
+	Limit          int32
 }
 
 type GetPostsForUserRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
-	FeedName    string
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         sql.NullString
+	PublishedAt         sql.NullTime
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Content             sql.NullString
+	Guid                string
+	ContentHash         string
+	CanonicalUrl        string
+	FeedName            string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Author,
+		arg.Category,
+		arg.WithEnclosures,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +101,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.PublishedAtInferred,
+			&i.Content,
+			&i.Guid,
+			&i.ContentHash,
+			&i.CanonicalUrl,
 			&i.FeedName,
 		); err != nil {
 			return nil, err
@@ -115,3 +120,82 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const rekeyPostByLink = `-- name: RekeyPostByLink :exec
+
+
+UPDATE posts
+SET guid = $1
+WHERE posts.feed_id = $2
+AND posts.guid = $3
+AND posts.guid <> $1
+AND NOT EXISTS (
+  SELECT 1 FROM posts AS keyed
+  WHERE keyed.feed_id = $2
+  AND keyed.guid = $1
+)
+`
+
+type RekeyPostByLinkParams struct {
+	Guid     string
+	FeedID   uuid.UUID
+	LinkGuid string
+}
+
+func (q *Queries) RekeyPostByLink(ctx context.Context, arg RekeyPostByLinkParams) error {
+	_, err := q.db.ExecContext(ctx, rekeyPostByLink, arg.Guid, arg.FeedID, arg.LinkGuid)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, content, guid, content_hash, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+url = EXCLUDED.url,
+canonical_url = EXCLUDED.canonical_url,
+description = EXCLUDED.description,
+content = EXCLUDED.content,
+content_hash = EXCLUDED.content_hash,
+updated_at = CASE WHEN posts.content_hash = '' THEN posts.updated_at ELSE EXCLUDED.updated_at END
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id
+`
+
+type UpsertPostParams struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         sql.NullString
+	PublishedAt         sql.NullTime
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Content             sql.NullString
+	Guid                string
+	ContentHash         string
+	CanonicalUrl        string
+}
+
+// Rows from before content hashing get their hash without counting as edited.
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.PublishedAtInferred,
+		arg.Content,
+		arg.Guid,
+		arg.ContentHash,
+		arg.CanonicalUrl,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+type jsonFeed struct {
+  Version     string         `json:"version"`
+  Title       string         `json:"title"`
+  HomePageURL string         `json:"home_page_url"`
+  Description string         `json:"description"`
+  Icon        string         `json:"icon"`
+  Favicon     string         `json:"favicon"`
+  Language    string         `json:"language"`
+  Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+  ID            string `json:"id"`
+  URL           string `json:"url"`
+  ExternalURL   string `json:"external_url"`
+  Title         string `json:"title"`
+  ContentHTML   string `json:"content_html"`
+  ContentText   string `json:"content_text"`
+  Summary       string `json:"summary"`
+  DatePublished string `json:"date_published"`
+  DateModified  string `json:"date_modified"`
+  Tags          []string `json:"tags"`
+  // JSON Feed 1.0 had a single author; 1.1 replaced it with authors.
+  Author      *jsonFeedAuthor     `json:"author"`
+  Authors     []jsonFeedAuthor    `json:"authors"`
+  Attachments []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAuthor struct {
+  Name string `json:"name"`
+}
+
+type jsonFeedAttachment struct {
+  URL         string `json:"url"`
+  MimeType    string `json:"mime_type"`
+  SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+// isJSONFeed reports whether a response should be decoded as JSON Feed,
+// going by the Content-Type first and the first byte of the body otherwise.
+func isJSONFeed(contentType string, body []byte) bool {
+  contentType = strings.ToLower(contentType)
+  if strings.Contains(contentType, "application/feed+json") || strings.Contains(contentType, "application/json") {
+    return true
+  }
+
+  trimmed := strings.TrimSpace(string(body))
+  return strings.HasPrefix(trimmed, "{")
+}
+
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+
+  var feed jsonFeed
+
+  err := json.Unmarshal(body, &feed)
+  if err != nil {
+    return nil, err
+  }
+
+  // Without this any JSON object, an API error included, would pass for an
+  // empty feed.
+  if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+    return nil, fmt.Errorf("not a JSON Feed: version is %q", feed.Version)
+  }
+
+  var rssFeed RSSFeed
+  rssFeed.Channel.Title = feed.Title
+  rssFeed.Channel.Link = feed.HomePageURL
+  rssFeed.Channel.Description = feed.Description
+  rssFeed.Channel.Language = feed.Language
+
+  rssFeed.Channel.Images = []rssImage{{URL: feed.Icon}, {URL: feed.Favicon}}
+
+  for _, item := range feed.Items {
+    link := item.URL
+    if link == "" {
+      link = item.ExternalURL
+    }
+    // id is only required to be unique, but many publishers use the permalink.
+    if link == "" && strings.HasPrefix(item.ID, "http") {
+      link = item.ID
+    }
+
+    content := item.ContentHTML
+    if content == "" {
+      content = item.ContentText
+    }
+
+    description := item.Summary
+    if description == "" {
+      description = content
+    }
+
+    pubDate := item.DatePublished
+    if pubDate == "" {
+      pubDate = item.DateModified
+    }
+
+    rssItem := RSSItem{
+      Title:       item.Title,
+      Link:        link,
+      Description: description,
+      PubDate:     pubDate,
+      Content:     content,
+      GUID:        item.ID,
+      Categories:  item.Tags,
+    }
+
+    if item.Author != nil {
+      rssItem.Authors = append(rssItem.Authors, item.Author.Name)
+    }
+    for _, author := range item.Authors {
+      rssItem.Authors = append(rssItem.Authors, author.Name)
+    }
+    for _, attachment := range item.Attachments {
+      length := ""
+      if attachment.SizeInBytes > 0 {
+        length = strconv.FormatInt(attachment.SizeInBytes, 10)
+      }
+      rssItem.Enclosures = append(rssItem.Enclosures, RSSEnclosure{URL: attachment.URL, Type: attachment.MimeType, Length: length})
+    }
+
+    rssFeed.Channel.Item = append(rssFeed.Channel.Item, rssItem)
+  }
+
+  return &rssFeed, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+const sampleJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Example Podcast",
+  "home_page_url": "https://example.com/",
+  "feed_url": "https://example.com/feed.json",
+  "description": "Episodes about examples",
+  "icon": "https://example.com/icon.png",
+  "favicon": "https://example.com/favicon.ico",
+  "language": "en",
+  "items": [
+    {
+      "id": "episode-1",
+      "url": "https://example.com/episodes/1",
+      "title": "Episode 1",
+      "summary": "Short notes",
+      "content_html": "<p>Full notes</p>",
+      "content_text": "Full notes",
+      "date_published": "2024-05-01T09:00:00Z",
+      "date_modified": "2024-05-02T09:00:00Z",
+      "tags": ["audio", "examples"],
+      "authors": [{"name": "Ada"}, {"name": "Grace"}],
+      "attachments": [
+        {"url": "https://example.com/episodes/1.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 5000},
+        {"url": "https://example.com/episodes/1.txt", "mime_type": "text/plain"}
+      ]
+    },
+    {
+      "id": "https://example.com/notes/2",
+      "content_text": "Plain text only",
+      "date_modified": "2024-05-03T09:00:00Z",
+      "author": {"name": "Linus"}
+    },
+    {
+      "id": "3",
+      "external_url": "https://elsewhere.example.org/story",
+      "title": "Linked"
+    }
+  ]
+}`
+
+func TestParseJSONFeed(t *testing.T) {
+
+  feed, err := parseFeed([]byte(sampleJSONFeed), "application/feed+json")
+  if err != nil {
+    t.Fatalf("parseFeed: %v", err)
+  }
+
+  channel := feed.Channel
+  if channel.Title != "Example Podcast" || channel.Link != "https://example.com/" {
+    t.Errorf("title, link = %q, %q", channel.Title, channel.Link)
+  }
+  if channel.Description != "Episodes about examples" || channel.Language != "en" {
+    t.Errorf("description, language = %q, %q", channel.Description, channel.Language)
+  }
+  wantImages := []rssImage{{URL: "https://example.com/icon.png"}, {URL: "https://example.com/favicon.ico"}}
+  if !reflect.DeepEqual(channel.Images, wantImages) {
+    t.Errorf("images = %v; want %v", channel.Images, wantImages)
+  }
+
+  if len(channel.Item) != 3 {
+    t.Fatalf("got %d items; want 3", len(channel.Item))
+  }
+
+  wantFirst := RSSItem{
+    Title:       "Episode 1",
+    Link:        "https://example.com/episodes/1",
+    Description: "Short notes",
+    PubDate:     "2024-05-01T09:00:00Z",
+    GUID:        "episode-1",
+    Content:     "<p>Full notes</p>",
+    Authors:     []string{"Ada", "Grace"},
+    Categories:  []string{"audio", "examples"},
+    Enclosures: []RSSEnclosure{
+      {URL: "https://example.com/episodes/1.mp3", Type: "audio/mpeg", Length: "5000"},
+      {URL: "https://example.com/episodes/1.txt", Type: "text/plain"},
+    },
+  }
+  if !reflect.DeepEqual(channel.Item[0], wantFirst) {
+    t.Errorf("first item = %+v\nwant %+v", channel.Item[0], wantFirst)
+  }
+
+  // A 1.0 author, a permalink id and content_text standing in for the rest.
+  wantSecond := RSSItem{
+    Link:        "https://example.com/notes/2",
+    Description: "Plain text only",
+    PubDate:     "2024-05-03T09:00:00Z",
+    GUID:        "https://example.com/notes/2",
+    Content:     "Plain text only",
+    Authors:     []string{"Linus"},
+  }
+  if !reflect.DeepEqual(channel.Item[1], wantSecond) {
+    t.Errorf("second item = %+v\nwant %+v", channel.Item[1], wantSecond)
+  }
+
+  if channel.Item[2].Link != "https://elsewhere.example.org/story" {
+    t.Errorf("third item link = %q; want the external_url", channel.Item[2].Link)
+  }
+}
+
+func TestParseJSONFeedVersion(t *testing.T) {
+
+  tests := []struct {
+    body string
+    ok   bool
+  }{
+    {`{"version": "https://jsonfeed.org/version/1", "title": "Old", "items": []}`, true},
+    {`{"version": "https://jsonfeed.org/version/1.1", "items": []}`, true},
+    {`{"error": "not found"}`, false},
+    {`{"version": "1.1", "items": []}`, false},
+  }
+
+  for _, test := range tests {
+    _, err := parseFeed([]byte(test.body), "application/json")
+    if (err == nil) != test.ok {
+      t.Errorf("parseFeed(%s) error = %v; want ok %v", test.body, err, test.ok)
+    }
+  }
+}
+
+func TestIsJSONFeed(t *testing.T) {
+
+  tests := []struct {
+    contentType string
+    body        string
+    want        bool
+  }{
+    {"application/feed+json; charset=utf-8", "", true},
+    {"application/json", "", true},
+    {"", "  \n{\"version\": \"\"}", true},
+    {"text/plain", "{}", true},
+    {"application/rss+xml", "<rss></rss>", false},
+    {"", "<?xml version=\"1.0\"?><feed/>", false},
+  }
+
+  for _, test := range tests {
+    got := isJSONFeed(test.contentType, []byte(test.body))
+    if got != test.want {
+      t.Errorf("isJSONFeed(%q, %q) = %v; want %v", test.contentType, test.body, got, test.want)
+    }
+  }
+}
@@ -4,12 +4,12 @@ import (
     "github.com/John-1005/BlogAggregator/internal/config"
     "github.com/John-1005/BlogAggregator/internal/database"
     "fmt"
-    "log"
     "os"
     "time"
-    "strings"
     "strconv"
+    "strings"
     "database/sql"
+    "errors"
     "github.com/google/uuid"
     "context"
     "github.com/lib/pq"
@@ -18,7 +18,9 @@ import (
 
 type state struct {
   db *database.Queries
+  conn *sql.DB
   cfg *config.Config
+  client *feedClient
 }
 
 type command struct {
@@ -52,7 +54,13 @@ func main(){
 
   var s state
   s.db = dbQueries
+  s.conn = db
   s.cfg = &configRead
+  s.client, err = newFeedClient(s.cfg)
+  if err != nil {
+    fmt.Println(err)
+    os.Exit(1)
+  }
 
   var c commands
 
@@ -65,10 +73,15 @@ func main(){
   c.register("agg", handlerAgg)
   c.register("addfeed", middlewareLoggedIn(handleraddFeed))
   c.register("feeds", handlerFeeds)
+  c.register("feed", handlerFeed)
   c.register("follow", middlewareLoggedIn(handlerFollow))
   c.register("following", middlewareLoggedIn(handlerFollowing))
   c.register("unfollow", middlewareLoggedIn(handlerUnfollow))
   c.register("browse", middlewareLoggedIn(handlerBrowse))
+  c.register("feedstats", handlerFeedStats)
+  c.register("revisions", handlerRevisions)
+  c.register("download", middlewareLoggedIn(handlerDownload))
+  c.register("downloads", middlewareLoggedIn(handlerDownloads))
 
   if len(os.Args) < 2 {
     fmt.Println("expected a command")
@@ -214,102 +227,40 @@ func handlerUsers (s *state, cmd command) error {
 }
 
 
-func handlerAgg(s* state, cmd command) error {
-  if len(cmd.args) == 0 {
-    return fmt.Errorf("expected command")
+func handleraddFeed(s *state, cmd command, user database.User) error {
+  if len(cmd.args) != 1 && len(cmd.args) != 2 {
+    fmt.Println("requires a url, optionally preceded by a name")
+    os.Exit(1)
   }
+  feedArg := cmd.args[len(cmd.args)-1]
 
-  durationString := cmd.args[0]
-
-  duration, err := time.ParseDuration(durationString)
-
+  // Test-fetch before storing anything. People often paste the blog's
+  // homepage rather than its feed, so this also finds the real one.
+  page, err := resolveFeed(context.Background(), s.client, feedArg)
   if err != nil {
     return err
   }
-
-  ticker := time.NewTicker(duration)
-  
-  for ; ; <- ticker.C {
-    scrapeFeeds(s)
-  }
-
-  return nil
-
-}
-
-func scrapeFeeds(s *state) error {
-
-  fetchedFeed, err := s.db.GetNextFeedToFetch(context.Background())
-
-  if err != nil {
-    log.Printf("Error getting next feed to fetch %v", err)
-    return nil
+  url := page.URL
+  if url != feedArg {
+    fmt.Printf("Using feed %s\n", url)
   }
 
-  _, err = s.db.MarkedFeedFetch(context.Background(), fetchedFeed.ID)
-
-  if err != nil {
-    log.Printf("Error marking feed %s as fetched %v ", fetchedFeed.Url, err)
-    return nil
+  // The same feed under another spelling of its URL would only be a
+  // duplicate.
+  _, err = s.db.GetFeedByUrl(context.Background(), canonicalURL(url))
+  if err == nil {
+    return fmt.Errorf("%s has already been added, follow it instead", url)
   }
-
-
-  feed, err := fetchFeed(context.Background(), fetchedFeed.Url)
-
-  if err != nil {
-    log.Printf("Error fetching feed %s: %v", fetchedFeed.Url, err)
-    return nil
+  if !errors.Is(err, sql.ErrNoRows) {
+    return fmt.Errorf("Error getting feed: %w", err)
   }
 
-  for _, item := range feed.Channel.Item {
-
-    uniqueID := uuid.New()
-    title := item.Title
-    publishedAt := sql.NullTime{}
-    if time, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
-      publishedAt = sql.NullTime{
-        Time:   time,
-        Valid: true,
-      }
-    }
-
-    _, err = s.db.CreatePost(
-      context.Background(),
-      database.CreatePostParams{
-        ID: uniqueID,
-        CreatedAt: time.Now().UTC(),
-        UpdatedAt: time.Now().UTC(),
-        Title: title,
-        Url: item.Link,
-        Description: sql.NullString{
-            String: item.Description,
-            Valid: true,
-        },
-        PublishedAt: publishedAt,
-        FeedID: fetchedFeed.ID,
-        },
-      )
-
-    if err != nil {
-      if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-        continue
-      }
-      log.Printf("Couldn't create post: %v", err)
-      continue
-    }
+  channel := page.Feed.Channel
+  name := feedName(page)
+  if len(cmd.args) == 2 {
+    name = cmd.args[0]
   }
 
-  log.Printf("Feed %s collected, %v posts found", feed.Channel.Title, len(feed.Channel.Item))
-  return nil
-}
-
-func handleraddFeed(s *state, cmd command, user database.User) error {
-  if len(cmd.args) != 2 {
-    fmt.Println("requires 2 arguments: name and url")
-    os.Exit(1)
-  }
-  name := cmd.args[0]
-  url := cmd.args[1]
   t:= time.Now().UTC()
   uniqueID := uuid.New()
 
@@ -323,12 +274,18 @@ func handleraddFeed(s *state, cmd command, user database.User) error {
       CreatedAt: t,
       UpdatedAt: t,
       Url:       url,
+      CanonicalUrl: canonicalURL(url),
     },
   )
   if err != nil {
     return fmt.Errorf("Unable to create feed: %w", err)
   }
 
+  err = updateFeedMetadata(context.Background(), s, feed.ID, page.FinalURL, page.Feed)
+  if err != nil {
+    return err
+  }
+
   fID := feed.ID
 
   feedFollow, err := s.db.CreateFeedFollows(
@@ -345,7 +302,22 @@ func handleraddFeed(s *state, cmd command, user database.User) error {
     return fmt.Errorf("Unable to create follow: %w", err)
   }
 
-  fmt.Printf("Feed created successfully: %+v", feedFollow)
+  fmt.Printf("Feed created successfully: %+v\n", feedFollow)
+
+  // The test fetch already has the posts, so there's no waiting for agg.
+  created := 0
+  for _, item := range channel.Item {
+    outcome, err := storePost(context.Background(), s, feed.ID, item)
+    if err != nil {
+      fmt.Printf("Couldn't store post: %v\n", err)
+      continue
+    }
+    if outcome == postCreated {
+      created++
+    }
+  }
+
+  fmt.Printf("Added %d posts from %s\n", created, name)
   return nil
 
 }
@@ -362,6 +334,17 @@ func handlerFeeds(s *state, cmd command) error {
 
   for _, item := range feeds {
     fmt.Printf("Feed: %s, URL: %s, Created by: %s\n", item.FeedName, item.Url, item.Name)
+    if item.Title.Valid && item.Title.String != item.FeedName {
+      fmt.Printf("    Title: %s\n", item.Title.String)
+    }
+    if item.SiteLink.Valid || item.Language.Valid {
+      fmt.Printf("    Site: %s, language: %s\n", orUnknown(item.SiteLink), orUnknown(item.Language))
+    }
+    if item.DisabledAt.Valid {
+      fmt.Printf("    Disabled since %s: %s\n", item.DisabledAt.Time.Format(time.RFC1123), item.LastError.String)
+    } else if item.ConsecutiveFailures > 0 {
+      fmt.Printf("    %d failed fetches in a row, last error: %s\n", item.ConsecutiveFailures, item.LastError.String)
+    }
   }
   return nil
 
@@ -377,7 +360,7 @@ func handlerFollow(s *state, cmd command, user database.User) error {
   url := cmd.args[0]
 
 
-  getFeedByUrl, err := s.db.GetFeedByUrl(context.Background(), url)
+  getFeedByUrl, err := s.db.GetFeedByUrl(context.Background(), canonicalURL(url))
   if err != nil {
     return fmt.Errorf("Error getting feed: %w", err)
   }
@@ -413,7 +396,7 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 
   url := cmd.args[0]
 
-  feedID, err := s.db.GetFeedByUrl(context.Background(), url)
+  feedID, err := s.db.GetFeedByUrl(context.Background(), canonicalURL(url))
 
   if err != nil {
     return err
@@ -439,7 +422,7 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 
   followingUser, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
   if err != nil {
-    return fmt.Errorf("Error getting follows", err)
+    return fmt.Errorf("Error getting follows: %w", err)
   }
 
   for _, following := range followingUser {
@@ -452,8 +435,31 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
   limit := 2
-  if len(cmd.args) > 0 {
-    if cmdLimit, err := strconv.Atoi(cmd.args[0]); err == nil {
+  full := false
+  var author, category sql.NullString
+  withEnclosures := false
+  for _, arg := range cmd.args {
+    if arg == "full" || arg == "--full" {
+      full = true
+      continue
+    }
+
+    if arg == "enclosures" || arg == "--enclosures" {
+      withEnclosures = true
+      continue
+    }
+
+    if value, ok := strings.CutPrefix(arg, "author="); ok {
+      author = sql.NullString{String: value, Valid: true}
+      continue
+    }
+
+    if value, ok := strings.CutPrefix(arg, "category="); ok {
+      category = sql.NullString{String: value, Valid: true}
+      continue
+    }
+
+    if cmdLimit, err := strconv.Atoi(arg); err == nil {
       limit = cmdLimit
     } else {
       return fmt.Errorf("invalid limit: %w", err)
@@ -465,6 +471,9 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
     context.Background(),
     database.GetPostsForUserParams{
       UserID: user.ID,
+      Author: author,
+      Category: category,
+      WithEnclosures: withEnclosures,
       Limit: int32(limit),
     },
   )
@@ -472,13 +481,75 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
     return fmt.Errorf("couldn't get posts for user: %s", err)
   }
 
+  notifications, err := s.db.GetUnreadNotifications(context.Background(), user.ID)
+  if err != nil {
+    return fmt.Errorf("couldn't get notifications: %w", err)
+  }
+
+  for _, notification := range notifications {
+    fmt.Printf("!!! %s: %s\n", notification.CreatedAt.Format("Mon Jan 2"), notification.Message)
+  }
+
+  if len(notifications) > 0 {
+    err = s.db.MarkNotificationsRead(context.Background(), user.ID)
+    if err != nil {
+      return fmt.Errorf("couldn't mark notifications read: %w", err)
+    }
+  }
+
   fmt.Printf("Found %d posts for user: %s:\n", len(posts), user.Name)
   for _, post := range posts {
-    fmt.Printf("%s from %s\n", post.PublishedAt.Time.Format("Mon Jan 2"), post.FeedName)
+    published := post.PublishedAt.Time.Format("Mon Jan 2")
+    if post.PublishedAtInferred {
+      published += " (date inferred)"
+    }
+    if post.UpdatedAt.After(post.CreatedAt) {
+      published += " (edited)"
+    }
+    fmt.Printf("%s from %s\n", published, post.FeedName)
 		fmt.Printf("--- %s ---\n", post.Title)
-		fmt.Printf("    %v\n", post.Description.String)
+		if full && post.Content.Valid {
+			fmt.Printf("    %v\n", post.Content.String)
+		} else {
+			fmt.Printf("    %v\n", post.Description.String)
+		}
 		fmt.Printf("Link: %s\n", post.Url)
+		err = printPostMetadata(s, post.ID)
+		if err != nil {
+			return err
+		}
 		fmt.Println("*********************")
   }
   return nil
 }
+
+func handlerFeedStats(s *state, cmd command) error {
+  stats, err := s.db.GetFeedStats(context.Background())
+  if err != nil {
+    return fmt.Errorf("couldn't get feed stats: %w", err)
+  }
+
+  if len(stats) == 0 {
+    return fmt.Errorf("no feeds to list")
+  }
+
+  formatTime := func(t sql.NullTime) string {
+    if !t.Valid {
+      return "never"
+    }
+    return t.Time.Format(time.RFC1123)
+  }
+
+  for _, feed := range stats {
+    interval := "unknown"
+    if feed.PostIntervalSeconds.Valid {
+      interval = (time.Duration(feed.PostIntervalSeconds.Int64) * time.Second).String()
+    }
+
+    fmt.Printf("Feed: %s, URL: %s\n", feed.Name, feed.Url)
+    fmt.Printf("    Posts: %d, latest: %s\n", feed.PostCount, formatTime(feed.LatestPostAt))
+    fmt.Printf("    Posts about every: %s\n", interval)
+    fmt.Printf("    Last fetched: %s, next fetch: %s\n", formatTime(feed.LastFetchedAt), formatTime(feed.NextFetchAt))
+  }
+  return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+    "regexp"
+    "strings"
+    "time"
+)
+
+// Zone abbreviations feeds commonly use. time.Parse only knows the offset of
+// the local zone's abbreviations and treats the rest as UTC, so these are
+// swapped for numeric offsets before parsing.
+var zoneOffsets = map[string]string{
+  "UT":   "+0000",
+  "UTC":  "+0000",
+  "GMT":  "+0000",
+  "Z":    "+0000",
+  "EST":  "-0500",
+  "EDT":  "-0400",
+  "CST":  "-0600",
+  "CDT":  "-0500",
+  "MST":  "-0700",
+  "MDT":  "-0600",
+  "PST":  "-0800",
+  "PDT":  "-0700",
+  "AKST": "-0900",
+  "AKDT": "-0800",
+  "HST":  "-1000",
+  "BST":  "+0100",
+  "IST":  "+0530",
+  "CET":  "+0100",
+  "CEST": "+0200",
+  "EET":  "+0200",
+  "EEST": "+0300",
+  "MSK":  "+0300",
+  "JST":  "+0900",
+  "KST":  "+0900",
+  "AEST": "+1000",
+  "AEDT": "+1100",
+  "NZST": "+1200",
+  "NZDT": "+1300",
+}
+
+var (
+  leadingWeekday = regexp.MustCompile(`^[A-Za-z]+\.?,\s*`)
+  gmtOffset      = regexp.MustCompile(`\s(?:GMT|UTC)([+-]\d{2}:?\d{2})$`)
+)
+
+// Layouts tried after the weekday has been stripped and zone names replaced.
+var dateLayouts = buildDateLayouts()
+
+func buildDateLayouts() []string {
+
+  layouts := []string{
+    time.RFC3339Nano,
+    time.RFC3339,
+    "2006-01-02T15:04:05-0700",
+    "2006-01-02T15:04:05.999999999-0700",
+    "2006-01-02T15:04Z07:00",
+    "2006-01-02T15:04:05",
+    "2006-01-02 15:04:05Z07:00",
+    "2006-01-02 15:04:05 -0700",
+    "2006-01-02 15:04:05 -07:00",
+    "2006-01-02 15:04:05",
+    "2006-01-02 15:04",
+    "2006-01-02",
+    time.ANSIC,
+    time.UnixDate,
+    time.RubyDate,
+    "Mon Jan _2 15:04:05 -0700 2006",
+    "02-Jan-06 15:04:05 -0700",
+    "January 2, 2006 15:04:05",
+    "January 2, 2006",
+    "Jan 2, 2006 15:04:05",
+    "Jan 2, 2006",
+    "2006/01/02 15:04:05",
+    "2006/01/02",
+  }
+
+  // RFC 822 style dates, with and without seconds, two or four digit years
+  // and full or short month names.
+  for _, date := range []string{"2 Jan 2006", "2 Jan 06", "2 January 2006", "2 January 06"} {
+    for _, clock := range []string{"15:04:05", "15:04"} {
+      for _, zone := range []string{" -0700", " -07:00", ""} {
+        layouts = append(layouts, date+" "+clock+zone)
+      }
+    }
+    layouts = append(layouts, date)
+  }
+
+  return layouts
+}
+
+// normalizeDate massages the variations seen in the wild into something the
+// layouts above can match.
+func normalizeDate(value string) string {
+
+  value = strings.Join(strings.Fields(value), " ")
+  value = leadingWeekday.ReplaceAllString(value, "")
+  value = gmtOffset.ReplaceAllString(value, " $1")
+
+  // The zone isn't always last: Unix date puts the year after it.
+  fields := strings.Fields(value)
+  for i, field := range fields {
+    if offset, ok := zoneOffsets[strings.ToUpper(field)]; ok {
+      fields[i] = offset
+    }
+  }
+
+  return strings.Join(fields, " ")
+}
+
+// parsePubDate parses a feed timestamp (pubDate, dc:date, Atom or JSON Feed
+// dates) into UTC. When nothing matches it returns fallback and reports the
+// date as inferred.
+func parsePubDate(value string, fallback time.Time) (time.Time, bool) {
+
+  value = strings.TrimSpace(value)
+  if value == "" {
+    return fallback.UTC(), true
+  }
+
+  // The normalized value goes first: time.Parse would read a zone name it
+  // doesn't know as UTC rather than fail.
+  for _, candidate := range []string{normalizeDate(value), value} {
+    for _, layout := range dateLayouts {
+      if t, err := time.Parse(layout, candidate); err == nil {
+        return t.UTC(), false
+      }
+    }
+  }
+
+  return fallback.UTC(), true
+}
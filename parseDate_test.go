@@ -0,0 +1,44 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+func TestParsePubDate(t *testing.T) {
+
+  fallback := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+  tests := []struct {
+    value    string
+    want     time.Time
+    inferred bool
+  }{
+    {"Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, time.January, 2, 22, 4, 5, 0, time.UTC), false},
+    {"Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC), false},
+    {"Tue, 10 Jun 2003 04:00:00 PST", time.Date(2003, time.June, 10, 12, 0, 0, 0, time.UTC), false},
+    {"Mon, 2 Jan 2006 15:04 EST", time.Date(2006, time.January, 2, 20, 4, 0, 0, time.UTC), false},
+    {"Sat, 07 Sep 2002 00:00:01 GMT+01:00", time.Date(2002, time.September, 6, 23, 0, 1, 0, time.UTC), false},
+    {"Thursday, 5 Mar 2020 10:00:00 +0000", time.Date(2020, time.March, 5, 10, 0, 0, 0, time.UTC), false},
+    {"  Wed,  03   Jan  2007 10:00:00  cest ", time.Date(2007, time.January, 3, 8, 0, 0, 0, time.UTC), false},
+    {"Mon Jan 2 15:04:05 PST 2006", time.Date(2006, time.January, 2, 23, 4, 5, 0, time.UTC), false},
+    {"Fri Mar 15 09:30:00 cet 2024", time.Date(2024, time.March, 15, 8, 30, 0, 0, time.UTC), false},
+    {"2006-01-02T15:04:05Z", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC), false},
+    {"2006-01-02T15:04:05.5+02:00", time.Date(2006, time.January, 2, 13, 4, 5, 500000000, time.UTC), false},
+    {"2006-01-02 15:04:05", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC), false},
+    {"2 January 2006", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC), false},
+    {"January 2, 2006", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC), false},
+    {"", fallback, true},
+    {"sometime last week", fallback, true},
+  }
+
+  for _, test := range tests {
+    got, inferred := parsePubDate(test.value, fallback)
+    if !got.Equal(test.want) || inferred != test.inferred {
+      t.Errorf("parsePubDate(%q) = %v, %v; want %v, %v", test.value, got, inferred, test.want, test.inferred)
+    }
+    if got.Location() != time.UTC {
+      t.Errorf("parsePubDate(%q) returned %v, not UTC", test.value, got.Location())
+    }
+  }
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "context"
+    "database/sql"
+    "fmt"
+    "strconv"
+    "strings"
+    "github.com/google/uuid"
+)
+
+// storePostMetadata brings the post's authors, categories and enclosures in
+// line with the item's. It runs on every fetch, whether or not the post
+// itself changed, so everything is upserted and only what the item no longer
+// has is deleted. Enclosures are updated in place rather than recreated so
+// anything referring to them survives an edit, including a new URL for the
+// same file. It returns the files of downloads whose enclosure was dropped,
+// for the caller to remove once the transaction commits.
+func storePostMetadata(ctx context.Context, qtx *database.Queries, postID uuid.UUID, item RSSItem) ([]sql.NullString, error) {
+
+  authors := itemAuthors(item)
+  for _, name := range authors {
+    err := qtx.CreatePostAuthor(
+      ctx,
+      database.CreatePostAuthorParams{
+        PostID: postID,
+        Name:   name,
+      },
+    )
+    if err != nil {
+      return nil, fmt.Errorf("couldn't store post author: %w", err)
+    }
+  }
+
+  err := qtx.DeleteStalePostAuthors(
+    ctx,
+    database.DeleteStalePostAuthorsParams{
+      PostID: postID,
+      // A nil slice would go over as NULL and keep every stale name.
+      Names:  append([]string{}, authors...),
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't remove old post authors: %w", err)
+  }
+
+  categories := itemCategories(item)
+  for _, name := range categories {
+    err = qtx.CreatePostCategory(
+      ctx,
+      database.CreatePostCategoryParams{
+        PostID: postID,
+        Name:   name,
+      },
+    )
+    if err != nil {
+      return nil, fmt.Errorf("couldn't store post category: %w", err)
+    }
+  }
+
+  err = qtx.DeleteStalePostCategories(
+    ctx,
+    database.DeleteStalePostCategoriesParams{
+      PostID: postID,
+      Names:  append([]string{}, categories...),
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't remove old post categories: %w", err)
+  }
+
+  urls := []string{}
+  for _, enclosure := range item.Enclosures {
+    enclosureURL := strings.TrimSpace(enclosure.URL)
+    if enclosureURL != "" {
+      urls = append(urls, enclosureURL)
+    }
+  }
+
+  existing, err := qtx.GetPostEnclosures(ctx, postID)
+  if err != nil {
+    return nil, fmt.Errorf("couldn't get post enclosures: %w", err)
+  }
+
+  for newURL, enclosure := range renamedEnclosures(existing, urls) {
+    err = qtx.UpdatePostEnclosureURL(
+      ctx,
+      database.UpdatePostEnclosureURLParams{
+        ID:  enclosure.ID,
+        Url: newURL,
+      },
+    )
+    if err != nil {
+      return nil, fmt.Errorf("couldn't update post enclosure url: %w", err)
+    }
+  }
+
+  for _, enclosure := range item.Enclosures {
+    enclosureURL := strings.TrimSpace(enclosure.URL)
+    if enclosureURL == "" {
+      continue
+    }
+
+    // Podcast feeds routinely put 0 or junk in length when they don't know it.
+    length, parseErr := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+
+    err = qtx.UpsertPostEnclosure(
+      ctx,
+      database.UpsertPostEnclosureParams{
+        ID:     uuid.New(),
+        PostID: postID,
+        Url:    enclosureURL,
+        MimeType: sql.NullString{
+          String: strings.TrimSpace(enclosure.Type),
+          Valid:  strings.TrimSpace(enclosure.Type) != "",
+        },
+        Length: sql.NullInt64{
+          Int64: length,
+          Valid: parseErr == nil && length > 0,
+        },
+      },
+    )
+    if err != nil {
+      return nil, fmt.Errorf("couldn't store post enclosure: %w", err)
+    }
+  }
+
+  // Deleting an enclosure deletes its download too, which would leave a
+  // finished file behind with nothing left to prune it.
+  stalePaths, err := qtx.GetStaleEnclosureDownloads(
+    ctx,
+    database.GetStaleEnclosureDownloadsParams{
+      PostID: postID,
+      Urls:   urls,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't get downloads of old post enclosures: %w", err)
+  }
+
+  err = qtx.DeleteStalePostEnclosures(
+    ctx,
+    database.DeleteStalePostEnclosuresParams{
+      PostID: postID,
+      Urls:   urls,
+    },
+  )
+  if err != nil {
+    return nil, fmt.Errorf("couldn't remove old post enclosures: %w", err)
+  }
+
+  return stalePaths, nil
+}
+
+// renamedEnclosures pairs each item enclosure URL the post doesn't have yet
+// with the stored enclosure it replaces, if any. Podcast hosts rotate
+// tracking parameters and prefixes all the time, so an enclosure counts as
+// the same when its canonical URL or, failing that, its file name matches
+// exactly one of the stored enclosures the item no longer lists.
+func renamedEnclosures(existing []database.PostEnclosure, urls []string) map[string]database.PostEnclosure {
+
+  listed := map[string]bool{}
+  for _, enclosureURL := range urls {
+    listed[enclosureURL] = true
+  }
+
+  var stale []database.PostEnclosure
+  for _, enclosure := range existing {
+    if listed[enclosure.Url] {
+      delete(listed, enclosure.Url)
+    } else {
+      stale = append(stale, enclosure)
+    }
+  }
+
+  renamed := map[string]database.PostEnclosure{}
+  taken := map[uuid.UUID]bool{}
+
+  for _, key := range []func(string) string{canonicalURL, enclosureFileName} {
+    for _, enclosureURL := range urls {
+      // Only URLs no stored enclosure has are left in listed.
+      if !listed[enclosureURL] || key(enclosureURL) == "" {
+        continue
+      }
+      if _, ok := renamed[enclosureURL]; ok {
+        continue
+      }
+
+      var matches []database.PostEnclosure
+      for _, enclosure := range stale {
+        if !taken[enclosure.ID] && key(enclosure.Url) == key(enclosureURL) {
+          matches = append(matches, enclosure)
+        }
+      }
+      if len(matches) == 1 {
+        renamed[enclosureURL] = matches[0]
+        taken[matches[0].ID] = true
+      }
+    }
+  }
+
+  return renamed
+}
+
+// enclosureFileName is the last segment of an http(s) URL's path, or "" when
+// it has none.
+func enclosureFileName(raw string) string {
+
+  parts := httpURLParts.FindStringSubmatch(strings.TrimSpace(raw))
+  if parts == nil {
+    return ""
+  }
+
+  trimmed := strings.TrimRight(parts[2], "/")
+  return trimmed[strings.LastIndex(trimmed, "/")+1:]
+}
+
+// printPostMetadata adds the post's authors, categories and enclosures to its
+// browse entry, leaving out whatever it has none of.
+func printPostMetadata(s *state, postID uuid.UUID) error {
+
+  authors, err := s.db.GetPostAuthors(context.Background(), postID)
+  if err != nil {
+    return fmt.Errorf("couldn't get post authors: %w", err)
+  }
+  if len(authors) > 0 {
+    fmt.Printf("By: %s\n", strings.Join(authors, ", "))
+  }
+
+  categories, err := s.db.GetPostCategories(context.Background(), postID)
+  if err != nil {
+    return fmt.Errorf("couldn't get post categories: %w", err)
+  }
+  if len(categories) > 0 {
+    fmt.Printf("Categories: %s\n", strings.Join(categories, ", "))
+  }
+
+  enclosures, err := s.db.GetPostEnclosures(context.Background(), postID)
+  if err != nil {
+    return fmt.Errorf("couldn't get post enclosures: %w", err)
+  }
+  for _, enclosure := range enclosures {
+    details := []string{}
+    if enclosure.MimeType.Valid {
+      details = append(details, enclosure.MimeType.String)
+    }
+    if enclosure.Length.Valid {
+      details = append(details, formatSize(enclosure.Length.Int64))
+    }
+    if len(details) > 0 {
+      fmt.Printf("Enclosure: %s (%s)\n", enclosure.Url, strings.Join(details, ", "))
+    } else {
+      fmt.Printf("Enclosure: %s\n", enclosure.Url)
+    }
+  }
+
+  return nil
+}
+
+// formatSize renders an enclosure length for display.
+func formatSize(bytes int64) string {
+  const unit = 1024
+  if bytes < unit {
+    return fmt.Sprintf("%d B", bytes)
+  }
+  div, exp := int64(unit), 0
+  for n := bytes / unit; n >= unit; n /= unit {
+    div *= unit
+    exp++
+  }
+  return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
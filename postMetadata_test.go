@@ -0,0 +1,62 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "testing"
+    "github.com/google/uuid"
+)
+
+func TestRenamedEnclosures(t *testing.T) {
+
+  kept := database.PostEnclosure{ID: uuid.New(), Url: "https://cdn.example.com/kept.mp3"}
+  tagged := database.PostEnclosure{ID: uuid.New(), Url: "https://cdn.example.com/ep1.mp3?utm_source=feed"}
+  prefixed := database.PostEnclosure{ID: uuid.New(), Url: "https://track.example.net/r/abc/cdn.example.com/ep2.mp3"}
+  gone := database.PostEnclosure{ID: uuid.New(), Url: "https://cdn.example.com/bonus.mp3"}
+  twinA := database.PostEnclosure{ID: uuid.New(), Url: "https://a.example.com/audio.mp3"}
+  twinB := database.PostEnclosure{ID: uuid.New(), Url: "https://b.example.com/audio.mp3"}
+
+  existing := []database.PostEnclosure{kept, tagged, prefixed, gone, twinA, twinB}
+  urls := []string{
+    "https://cdn.example.com/kept.mp3",
+    "https://cdn.example.com/ep1.mp3?utm_source=app",
+    "https://track.example.net/r/xyz/cdn.example.com/ep2.mp3",
+    "https://cdn.example.com/new.mp3",
+    // Two stored enclosures share this file name, so neither is picked.
+    "https://c.example.com/audio.mp3",
+  }
+
+  got := renamedEnclosures(existing, urls)
+
+  want := map[string]uuid.UUID{
+    "https://cdn.example.com/ep1.mp3?utm_source=app":          tagged.ID,
+    "https://track.example.net/r/xyz/cdn.example.com/ep2.mp3": prefixed.ID,
+  }
+  if len(got) != len(want) {
+    t.Errorf("renamedEnclosures = %v; want %v", got, want)
+  }
+  for newURL, id := range want {
+    if got[newURL].ID != id {
+      t.Errorf("renamedEnclosures[%q] = %q; want %s", newURL, got[newURL].Url, id)
+    }
+  }
+}
+
+func TestEnclosureFileName(t *testing.T) {
+
+  tests := []struct {
+    raw  string
+    want string
+  }{
+    {"https://example.com/shows/ep1.mp3?x=1", "ep1.mp3"},
+    {"https://example.com/shows/ep1/", "ep1"},
+    {"https://example.com", ""},
+    {"urn:example:ep1", ""},
+  }
+
+  for _, test := range tests {
+    got := enclosureFileName(test.raw)
+    if got != test.want {
+      t.Errorf("enclosureFileName(%q) = %q; want %q", test.raw, got, test.want)
+    }
+  }
+}
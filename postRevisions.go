@@ -0,0 +1,167 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "time"
+    "github.com/google/uuid"
+)
+
+type postOutcome int
+
+const (
+  postUnchanged postOutcome = iota
+  postCreated
+  postUpdated
+)
+
+// contentHash fingerprints the parts of an item a publisher might edit, so a
+// refetch can tell a corrected post from one it has already stored.
+func contentHash(item RSSItem) string {
+  sum := sha256.Sum256([]byte(item.Title + "\x00" + item.Description + "\x00" + item.Content))
+  return hex.EncodeToString(sum[:])
+}
+
+// storePost inserts an item or, when a post with the same guid already exists
+// and its content has changed, keeps the old version in post_revisions and
+// overwrites the post with the new one. Authors, categories and enclosures
+// are synced either way.
+func storePost(ctx context.Context, s *state, feedID uuid.UUID, item RSSItem) (postOutcome, error) {
+
+  t := time.Now().UTC()
+  published, inferred := parsePubDate(item.PubDate, t)
+  guid := itemID(item)
+  hash := contentHash(item)
+
+  tx, err := s.conn.BeginTx(ctx, nil)
+  if err != nil {
+    return postUnchanged, err
+  }
+  defer tx.Rollback()
+
+  qtx := s.db.WithTx(tx)
+
+  // Posts stored before guids, or before this item had one, are keyed by
+  // their link. Move such a post over to the item's id instead of storing
+  // the item a second time.
+  if linkGUID := canonicalURL(item.Link); linkGUID != "" && linkGUID != guid {
+    err = qtx.RekeyPostByLink(
+      ctx,
+      database.RekeyPostByLinkParams{
+        Guid:     guid,
+        FeedID:   feedID,
+        LinkGuid: linkGUID,
+      },
+    )
+    if err != nil {
+      return postUnchanged, fmt.Errorf("couldn't rekey post: %w", err)
+    }
+  }
+
+  revised, err := qtx.SavePostRevision(
+    ctx,
+    database.SavePostRevisionParams{
+      ID:          uuid.New(),
+      CreatedAt:   t,
+      FeedID:      feedID,
+      Guid:        guid,
+      ContentHash: hash,
+    },
+  )
+  if err != nil {
+    return postUnchanged, fmt.Errorf("couldn't save post revision: %w", err)
+  }
+
+  outcome := postCreated
+  if revised > 0 {
+    outcome = postUpdated
+  }
+
+  postID, err := qtx.UpsertPost(
+    ctx,
+    database.UpsertPostParams{
+      ID: uuid.New(),
+      CreatedAt: t,
+      UpdatedAt: t,
+      Title: item.Title,
+      Url: item.Link,
+      Description: sql.NullString{
+        String: item.Description,
+        Valid: true,
+      },
+      PublishedAt: sql.NullTime{
+        Time:  published,
+        Valid: true,
+      },
+      FeedID: feedID,
+      PublishedAtInferred: inferred,
+      Content: sql.NullString{
+        String: item.Content,
+        Valid: item.Content != "",
+      },
+      Guid: guid,
+      ContentHash: hash,
+      CanonicalUrl: canonicalURL(item.Link),
+    },
+  )
+  if errors.Is(err, sql.ErrNoRows) {
+    // The upsert leaves unchanged posts alone and returns nothing for them,
+    // but their tags or enclosures may still have changed.
+    outcome = postUnchanged
+    postID, err = qtx.GetPostID(
+      ctx,
+      database.GetPostIDParams{
+        FeedID: feedID,
+        Guid:   guid,
+      },
+    )
+  }
+  if err != nil {
+    return postUnchanged, err
+  }
+
+  stalePaths, err := storePostMetadata(ctx, qtx, postID, item)
+  if err != nil {
+    return postUnchanged, err
+  }
+
+  err = tx.Commit()
+  if err != nil {
+    return postUnchanged, err
+  }
+
+  removeDownloadFiles(stalePaths)
+
+  // postCreated also counts posts stored before hashing getting their first
+  // hash; the only way to tell them apart would be another query per item.
+  return outcome, nil
+}
+
+func handlerRevisions(s *state, cmd command) error {
+  if len(cmd.args) == 0 {
+    return fmt.Errorf("expected post url")
+  }
+
+  revisions, err := s.db.GetPostRevisionsByURL(context.Background(), canonicalURL(cmd.args[0]))
+  if err != nil {
+    return fmt.Errorf("couldn't get revisions: %w", err)
+  }
+
+  if len(revisions) == 0 {
+    return fmt.Errorf("no earlier versions of %s", cmd.args[0])
+  }
+
+  fmt.Printf("%d earlier versions of %s:\n", len(revisions), cmd.args[0])
+  for _, revision := range revisions {
+    fmt.Printf("Replaced %s, from %s\n", revision.CreatedAt.Format(time.RFC1123), revision.FeedName)
+    fmt.Printf("--- %s ---\n", revision.Title)
+    fmt.Printf("    %v\n", revision.Description.String)
+    fmt.Println("*********************")
+  }
+  return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+    "strings"
+)
+
+// rdfFeed is RSS 1.0, where items sit next to the channel instead of in it.
+type rdfFeed struct {
+  Channel struct {
+    Title       string `xml:"title"`
+    Link        string `xml:"link"`
+    Description string `xml:"description"`
+    Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+
+    UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+    UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+  } `xml:"channel"`
+  // RSS 1.0 puts the channel image next to the channel as well.
+  Image struct {
+    URL string `xml:"url"`
+  } `xml:"image"`
+  Item []rdfItem `xml:"item"`
+}
+
+type rdfItem struct {
+  About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+  Title       string `xml:"title"`
+  Link        string `xml:"link"`
+  Description string `xml:"description"`
+  Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+  Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+  Creator     []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+  Subject     []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+func parseRDF(body []byte) (*RSSFeed, error) {
+
+  var rdf rdfFeed
+
+  err := unmarshalXML(body, &rdf)
+  if err != nil {
+    return nil, err
+  }
+
+  var rssFeed RSSFeed
+  rssFeed.Channel.Title = rdf.Channel.Title
+  rssFeed.Channel.Link = strings.TrimSpace(rdf.Channel.Link)
+  rssFeed.Channel.Description = rdf.Channel.Description
+  rssFeed.Channel.Language = rdf.Channel.Language
+  rssFeed.Channel.Images = []rssImage{{URL: rdf.Image.URL}}
+  rssFeed.Channel.UpdatePeriod = rdf.Channel.UpdatePeriod
+  rssFeed.Channel.UpdateFrequency = rdf.Channel.UpdateFrequency
+
+  for _, item := range rdf.Item {
+    rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+      Title:       item.Title,
+      Link:        strings.TrimSpace(item.Link),
+      Description: item.Description,
+      PubDate:     strings.TrimSpace(item.Date),
+      Content:     item.Content,
+      GUID:        strings.TrimSpace(item.About),
+      Creators:    item.Creator,
+      Categories:  item.Subject,
+    })
+  }
+
+  return &rssFeed, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+const sampleRDF = `<?xml version="1.0" encoding="utf-8"?>
+<rdf:RDF
+  xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+  xmlns="http://purl.org/rss/1.0/"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"
+  xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"
+  xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel rdf:about="https://example.com/">
+    <title>Example Journal</title>
+    <link> https://example.com/ </link>
+    <description>An RSS 1.0 feed</description>
+    <dc:language>de</dc:language>
+    <sy:updatePeriod>daily</sy:updatePeriod>
+    <sy:updateFrequency>2</sy:updateFrequency>
+    <items>
+      <rdf:Seq>
+        <rdf:li rdf:resource="https://example.com/articles/1"/>
+      </rdf:Seq>
+    </items>
+  </channel>
+  <image rdf:about="https://example.com/logo.png">
+    <url>https://example.com/logo.png</url>
+  </image>
+  <item rdf:about="https://example.com/articles/1">
+    <title>An article</title>
+    <link>https://example.com/articles/1</link>
+    <description>Teaser</description>
+    <dc:date>2024-03-04T05:06:07+02:00</dc:date>
+    <dc:creator>Ada</dc:creator>
+    <dc:creator>Grace</dc:creator>
+    <dc:subject>history</dc:subject>
+    <content:encoded><![CDATA[<p>The full article</p>]]></content:encoded>
+  </item>
+</rdf:RDF>`
+
+func TestParseRDF(t *testing.T) {
+
+  feed, err := parseFeed([]byte(sampleRDF), "application/rdf+xml")
+  if err != nil {
+    t.Fatalf("parseFeed: %v", err)
+  }
+
+  channel := feed.Channel
+  if channel.Title != "Example Journal" || channel.Link != "https://example.com/" {
+    t.Errorf("title, link = %q, %q", channel.Title, channel.Link)
+  }
+  if channel.Description != "An RSS 1.0 feed" || channel.Language != "de" {
+    t.Errorf("description, language = %q, %q", channel.Description, channel.Language)
+  }
+  if channel.UpdatePeriod != "daily" || channel.UpdateFrequency != "2" {
+    t.Errorf("updatePeriod, updateFrequency = %q, %q", channel.UpdatePeriod, channel.UpdateFrequency)
+  }
+  wantImages := []rssImage{{URL: "https://example.com/logo.png"}}
+  if !reflect.DeepEqual(channel.Images, wantImages) {
+    t.Errorf("images = %v; want %v", channel.Images, wantImages)
+  }
+
+  if len(channel.Item) != 1 {
+    t.Fatalf("got %d items; want 1", len(channel.Item))
+  }
+
+  want := RSSItem{
+    Title:       "An article",
+    Link:        "https://example.com/articles/1",
+    Description: "Teaser",
+    PubDate:     "2024-03-04T05:06:07+02:00",
+    GUID:        "https://example.com/articles/1",
+    Content:     "<p>The full article</p>",
+    Creators:    []string{"Ada", "Grace"},
+    Categories:  []string{"history"},
+  }
+  if !reflect.DeepEqual(channel.Item[0], want) {
+    t.Errorf("item = %+v\nwant %+v", channel.Item[0], want)
+  }
+}
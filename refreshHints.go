@@ -0,0 +1,156 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// freshUntil reads Cache-Control max-age, falling back to Expires, and
+// returns the zero time when the response carries no freshness information.
+func freshUntil(header http.Header, now time.Time) time.Time {
+
+  for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+    directive = strings.ToLower(strings.TrimSpace(directive))
+    if directive == "no-cache" || directive == "no-store" {
+      return time.Time{}
+    }
+
+    if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+      if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+        return now.Add(time.Duration(seconds) * time.Second)
+      }
+    }
+  }
+
+  if expires, err := http.ParseTime(header.Get("Expires")); err == nil && expires.After(now) {
+    return expires
+  }
+
+  return time.Time{}
+}
+
+// syndicationInterval turns sy:updatePeriod and sy:updateFrequency (updates
+// per period) into a polling interval.
+func syndicationInterval(period, frequency string) time.Duration {
+
+  var length time.Duration
+  switch strings.ToLower(strings.TrimSpace(period)) {
+  case "hourly":
+    length = time.Hour
+  case "daily":
+    length = 24 * time.Hour
+  case "weekly":
+    length = 7 * 24 * time.Hour
+  case "monthly":
+    length = 30 * 24 * time.Hour
+  case "yearly":
+    length = 365 * 24 * time.Hour
+  default:
+    return 0
+  }
+
+  times, err := strconv.Atoi(strings.TrimSpace(frequency))
+  if err != nil || times < 1 {
+    times = 1
+  }
+
+  return length / time.Duration(times)
+}
+
+// refreshHints is what a feed says about how often to poll it. It is stored
+// with the feed, since a 304 response doesn't repeat it.
+type refreshHints struct {
+  TTL             string
+  UpdatePeriod    string
+  UpdateFrequency string
+  SkipHours       []string
+  SkipDays        []string
+}
+
+func feedRefreshHints(feed *RSSFeed) refreshHints {
+  return refreshHints{
+    TTL:             strings.TrimSpace(feed.Channel.TTL),
+    UpdatePeriod:    strings.TrimSpace(feed.Channel.UpdatePeriod),
+    UpdateFrequency: strings.TrimSpace(feed.Channel.UpdateFrequency),
+    // A nil slice would go over as NULL.
+    SkipHours: append([]string{}, feed.Channel.SkipHours...),
+    SkipDays:  append([]string{}, feed.Channel.SkipDays...),
+  }
+}
+
+func storedRefreshHints(feed database.Feed) refreshHints {
+  return refreshHints{
+    TTL:             feed.Ttl,
+    UpdatePeriod:    feed.UpdatePeriod,
+    UpdateFrequency: feed.UpdateFrequency,
+    SkipHours:       feed.SkipHours,
+    SkipDays:        feed.SkipDays,
+  }
+}
+
+// nextFetchTime works out when a feed should next be polled from the hints it
+// gave us: RSS ttl, sy:updatePeriod, HTTP caching headers and skipHours /
+// skipDays, plus how often it has been seen to post. We poll twice per
+// observed posting interval. The wait is clamped to [minInterval,
+// maxInterval].
+func nextFetchTime(now time.Time, hints refreshHints, fresh time.Time, postInterval, minInterval, maxInterval time.Duration) time.Time {
+
+  interval := minInterval
+
+  if postInterval/2 > interval {
+    interval = postInterval / 2
+  }
+
+  if fresh.After(now) && fresh.Sub(now) > interval {
+    interval = fresh.Sub(now)
+  }
+
+  if ttl, err := strconv.Atoi(strings.TrimSpace(hints.TTL)); err == nil && ttl > 0 {
+    if d := time.Duration(ttl) * time.Minute; d > interval {
+      interval = d
+    }
+  }
+
+  if d := syndicationInterval(hints.UpdatePeriod, hints.UpdateFrequency); d > interval {
+    interval = d
+  }
+
+  skipHours := map[int]bool{}
+  for _, hour := range hints.SkipHours {
+    if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil {
+      // skipHours are GMT, and some feeds use 24 for midnight.
+      skipHours[h%24] = true
+    }
+  }
+
+  skipDays := map[time.Weekday]bool{}
+  for _, day := range hints.SkipDays {
+    for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+      if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+        skipDays[weekday] = true
+      }
+    }
+  }
+
+  if interval > maxInterval {
+    interval = maxInterval
+  }
+
+  latest := now.Add(maxInterval)
+  next := now.Add(interval).UTC()
+
+  // Step forward an hour at a time out of any skipped hours or days, without
+  // ever waiting past maxInterval.
+  for next.Before(latest) && (skipHours[next.Hour()] || skipDays[next.Weekday()]) {
+    next = next.Truncate(time.Hour).Add(time.Hour)
+  }
+
+  if next.After(latest) {
+    next = latest
+  }
+
+  return next
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "testing"
+    "time"
+)
+
+func TestNextFetchTime(t *testing.T) {
+
+  // A Monday, 10:00 UTC.
+  now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+  minInterval, maxInterval := time.Minute, 24*time.Hour
+
+  tests := []struct {
+    name         string
+    hints        refreshHints
+    fresh        time.Time
+    postInterval time.Duration
+    want         time.Time
+  }{
+    {"no hints", refreshHints{}, time.Time{}, 0, now.Add(time.Minute)},
+    {"ttl", refreshHints{TTL: "90"}, time.Time{}, 0, now.Add(90 * time.Minute)},
+    {"updatePeriod", refreshHints{UpdatePeriod: "daily", UpdateFrequency: "4"}, time.Time{}, 0, now.Add(6 * time.Hour)},
+    {"max-age", refreshHints{}, now.Add(2 * time.Hour), 0, now.Add(2 * time.Hour)},
+    {"post interval", refreshHints{}, time.Time{}, 4 * time.Hour, now.Add(2 * time.Hour)},
+    {"skipHours", refreshHints{TTL: "60", SkipHours: []string{"11", "12"}}, time.Time{}, 0, now.Add(3 * time.Hour)},
+    {"skipDays", refreshHints{TTL: "1440", SkipDays: []string{"Tuesday"}}, time.Time{}, 0, now.Add(24 * time.Hour)},
+    {"clamped", refreshHints{UpdatePeriod: "weekly"}, time.Time{}, 0, now.Add(24 * time.Hour)},
+  }
+
+  for _, test := range tests {
+    got := nextFetchTime(now, test.hints, test.fresh, test.postInterval, minInterval, maxInterval)
+    if !got.Equal(test.want) {
+      t.Errorf("%s: nextFetchTime = %v; want %v", test.name, got, test.want)
+    }
+  }
+}
+
+func TestStoredRefreshHints(t *testing.T) {
+
+  feed := &RSSFeed{}
+  feed.Channel.TTL = " 120 "
+  feed.Channel.SkipDays = []string{"Saturday", "Sunday"}
+
+  hints := feedRefreshHints(feed)
+  if hints.SkipHours == nil {
+    t.Errorf("feedRefreshHints left SkipHours nil, which would be stored as NULL")
+  }
+
+  // What a 304 goes by is what the last full response said.
+  stored := storedRefreshHints(database.Feed{
+    Ttl:       hints.TTL,
+    SkipHours: hints.SkipHours,
+    SkipDays:  hints.SkipDays,
+  })
+
+  now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+  got := nextFetchTime(now, stored, time.Time{}, 0, time.Minute, 24*time.Hour)
+  if want := now.Add(2 * time.Hour); !got.Equal(want) {
+    t.Errorf("nextFetchTime from stored hints = %v; want %v", got, want)
+  }
+}
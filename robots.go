@@ -0,0 +1,220 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+  robotsCacheTTL = 24 * time.Hour
+  // robotsMaxBytes caps how much of a robots.txt we read.
+  robotsMaxBytes = 512 * 1024
+)
+
+type robotsRule struct {
+  path  string
+  allow bool
+}
+
+type robotsEntry struct {
+  rules     []robotsRule
+  fetchedAt time.Time
+}
+
+// robotsCache fetches and remembers robots.txt per scheme and host.
+type robotsCache struct {
+  mu        sync.Mutex
+  client    *http.Client
+  userAgent string
+  entries   map[string]*robotsEntry
+}
+
+// newRobotsCache fetches through transport, which is expected to apply the
+// host limiter and a timeout itself. Redirects are followed, since robots.txt
+// is often moved from http to https.
+func newRobotsCache(transport http.RoundTripper, userAgent string) *robotsCache {
+  return &robotsCache{
+    client:    &http.Client{Transport: transport},
+    userAgent: userAgent,
+    entries:   make(map[string]*robotsEntry),
+  }
+}
+
+// check returns an error when robots.txt on the target's host disallows it.
+func (c *robotsCache) check(ctx context.Context, target *url.URL) error {
+
+  rules, err := c.rulesFor(ctx, target)
+  if err != nil {
+    return err
+  }
+
+  if !robotsAllowed(rules, target.RequestURI()) {
+    return fmt.Errorf("%s is disallowed by robots.txt", target)
+  }
+  return nil
+}
+
+func (c *robotsCache) rulesFor(ctx context.Context, target *url.URL) ([]robotsRule, error) {
+
+  key := target.Scheme + "://" + strings.ToLower(target.Host)
+
+  c.mu.Lock()
+  entry, ok := c.entries[key]
+  c.mu.Unlock()
+
+  if ok && time.Since(entry.fetchedAt) < robotsCacheTTL {
+    return entry.rules, nil
+  }
+
+  rules, err := c.fetch(ctx, key+"/robots.txt")
+  if err != nil {
+    return nil, err
+  }
+
+  c.mu.Lock()
+  c.entries[key] = &robotsEntry{rules: rules, fetchedAt: time.Now()}
+  c.mu.Unlock()
+
+  return rules, nil
+}
+
+// fetch downloads robots.txt. A missing file means everything is allowed.
+// A server error or an unreachable server says nothing about what is
+// allowed, so it is reported as an error, which keeps the request from
+// going ahead, and isn't cached: the next request asks again.
+func (c *robotsCache) fetch(ctx context.Context, robotsURL string) ([]robotsRule, error) {
+
+  req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+  if err != nil {
+    return nil, nil
+  }
+  req.Header.Set("User-Agent", c.userAgent)
+
+  rsp, err := c.client.Do(req)
+  if err != nil {
+    if ctx.Err() != nil {
+      return nil, ctx.Err()
+    }
+    return nil, fmt.Errorf("couldn't fetch %s: %w", robotsURL, err)
+  }
+  defer rsp.Body.Close()
+
+  switch {
+  case rsp.StatusCode == http.StatusOK:
+  case rsp.StatusCode >= 500 || rsp.StatusCode == http.StatusTooManyRequests:
+    return nil, fmt.Errorf("couldn't fetch %s: %s", robotsURL, rsp.Status)
+  default:
+    return nil, nil
+  }
+
+  // Groups are matched on the product token, e.g. "gator" in "gator/1.0".
+  agent, _, _ := strings.Cut(strings.ToLower(c.userAgent), "/")
+  return parseRobots(io.LimitReader(rsp.Body, robotsMaxBytes), agent), nil
+}
+
+// parseRobots returns the rules of the group naming agent, or of the "*"
+// group when no group names it. Groups name agents by product token, matched
+// whole and ignoring case; "*" is the only wildcard.
+func parseRobots(r io.Reader, agent string) []robotsRule {
+
+  var specific, wildcard []robotsRule
+  var agents []string
+  foundSpecific := false
+  inRules := false
+
+  scanner := bufio.NewScanner(r)
+  for scanner.Scan() {
+    line, _, _ := strings.Cut(scanner.Text(), "#")
+    key, value, ok := strings.Cut(line, ":")
+    if !ok {
+      continue
+    }
+    key = strings.ToLower(strings.TrimSpace(key))
+    value = strings.TrimSpace(value)
+
+    switch key {
+    case "user-agent":
+      // A user-agent line after rules starts a new group.
+      if inRules {
+        agents = nil
+        inRules = false
+      }
+      agents = append(agents, strings.ToLower(value))
+    case "allow", "disallow":
+      inRules = true
+      rule := robotsRule{path: value, allow: key == "allow"}
+      for _, name := range agents {
+        // Some files give a version too, as in "gator/1.0".
+        token, _, _ := strings.Cut(name, "/")
+        if name == "*" {
+          wildcard = append(wildcard, rule)
+        } else if token != "" && token == agent {
+          specific = append(specific, rule)
+          foundSpecific = true
+        }
+      }
+    }
+  }
+
+  if foundSpecific {
+    return specific
+  }
+  return wildcard
+}
+
+// robotsAllowed applies the longest matching rule, with Allow winning ties.
+func robotsAllowed(rules []robotsRule, path string) bool {
+
+  allowed := true
+  longest := -1
+
+  for _, rule := range rules {
+    if rule.path == "" || !robotsMatch(rule.path, path) {
+      continue
+    }
+    if len(rule.path) > longest || (len(rule.path) == longest && rule.allow) {
+      longest = len(rule.path)
+      allowed = rule.allow
+    }
+  }
+
+  return allowed
+}
+
+// robotsMatch supports the common extensions: * for any run of characters
+// and a trailing $ to anchor the end of the path.
+func robotsMatch(pattern, path string) bool {
+
+  anchored := strings.HasSuffix(pattern, "$")
+  pattern = strings.TrimSuffix(pattern, "$")
+
+  parts := strings.Split(pattern, "*")
+  if !strings.HasPrefix(path, parts[0]) {
+    return false
+  }
+  rest := path[len(parts[0]):]
+
+  for i, part := range parts[1:] {
+    // An anchored last part has to match at the very end.
+    if anchored && i == len(parts)-2 {
+      return strings.HasSuffix(rest, part)
+    }
+    index := strings.Index(rest, part)
+    if index < 0 {
+      return false
+    }
+    rest = rest[index+len(part):]
+  }
+
+  if anchored {
+    return rest == ""
+  }
+  return true
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestParseRobots(t *testing.T) {
+
+  body := `# comments and blank lines are ignored
+
+User-agent: *
+Disallow: /private/
+
+User-agent: otherbot
+User-agent: gator
+Disallow: /drafts/  # trailing comment
+Allow: /drafts/public
+
+User-agent: somebot
+Disallow: /
+`
+
+  rules := parseRobots(strings.NewReader(body), "gator")
+  want := []robotsRule{
+    {path: "/drafts/", allow: false},
+    {path: "/drafts/public", allow: true},
+  }
+  if len(rules) != len(want) {
+    t.Fatalf("parseRobots for gator = %v; want %v", rules, want)
+  }
+  for i := range want {
+    if rules[i] != want[i] {
+      t.Errorf("parseRobots for gator rule %d = %v; want %v", i, rules[i], want[i])
+    }
+  }
+
+  rules = parseRobots(strings.NewReader(body), "unknownbot")
+  if len(rules) != 1 || rules[0] != (robotsRule{path: "/private/"}) {
+    t.Errorf("parseRobots for unknownbot = %v; want the * group", rules)
+  }
+
+  // Only a whole product token names us.
+  body = `User-agent: *
+Disallow: /wildcard
+
+User-agent:
+Disallow: /empty
+
+User-agent: gat
+User-agent: gatorbot
+User-agent: a
+Disallow: /partial
+
+User-agent: GATOR/2.0
+Disallow: /ours
+`
+  rules = parseRobots(strings.NewReader(body), "gator")
+  if len(rules) != 1 || rules[0] != (robotsRule{path: "/ours"}) {
+    t.Errorf("parseRobots for gator = %v; want only the GATOR/2.0 group", rules)
+  }
+
+  rules = parseRobots(strings.NewReader(body), "otherbot")
+  if len(rules) != 1 || rules[0] != (robotsRule{path: "/wildcard"}) {
+    t.Errorf("parseRobots for otherbot = %v; want only the * group", rules)
+  }
+}
+
+func TestRobotsAllowed(t *testing.T) {
+
+  rules := []robotsRule{
+    {path: "/private/", allow: false},
+    {path: "/private/open", allow: true},
+    {path: "/*.pdf$", allow: false},
+    {path: "/tie", allow: false},
+    {path: "/tie", allow: true},
+    {path: "", allow: false},
+  }
+
+  tests := []struct {
+    path string
+    want bool
+  }{
+    {"/", true},
+    {"/feed.xml", true},
+    {"/private/", false},
+    {"/private/notes", false},
+    {"/private/open/feed", true},
+    {"/files/report.pdf", false},
+    {"/files/report.pdf?x=1", true},
+    {"/tie", true},
+  }
+
+  for _, test := range tests {
+    got := robotsAllowed(rules, test.path)
+    if got != test.want {
+      t.Errorf("robotsAllowed(%q) = %v; want %v", test.path, got, test.want)
+    }
+  }
+
+  if !robotsAllowed(nil, "/anything") {
+    t.Errorf("robotsAllowed with no rules should allow everything")
+  }
+}
+
+func TestRobotsMatch(t *testing.T) {
+
+  tests := []struct {
+    pattern string
+    path    string
+    want    bool
+  }{
+    {"/a", "/a", true},
+    {"/a", "/abc", true},
+    {"/a", "/b", false},
+    {"/a$", "/a", true},
+    {"/a$", "/ab", false},
+    {"/*/feed", "/blog/feed", true},
+    {"/*/feed", "/blog/posts", false},
+    {"/*.xml$", "/blog/feed.xml", true},
+    {"/*.xml$", "/blog/feed.xml.bak", false},
+    {"/a*b*c", "/axxbyyc", true},
+    {"/a*b*c", "/axxcyyb", false},
+    {"*", "/anything", true},
+  }
+
+  for _, test := range tests {
+    got := robotsMatch(test.pattern, test.path)
+    if got != test.want {
+      t.Errorf("robotsMatch(%q, %q) = %v; want %v", test.pattern, test.path, got, test.want)
+    }
+  }
+}
+
+// newTestRobotsCache fetches robots.txt with the host limiter out of the way.
+func newTestRobotsCache() *robotsCache {
+  return newRobotsCache(
+    &politeTransport{
+      base:    http.DefaultTransport,
+      limiter: newHostLimiter(60000, 100, 0),
+      timeout: 5 * time.Second,
+    },
+    "gator/1.0",
+  )
+}
+
+func TestRobotsCacheCheck(t *testing.T) {
+
+  var fetches atomic.Int32
+  status := http.StatusOK
+
+  mux := http.NewServeMux()
+  mux.Handle("/robots.txt", http.RedirectHandler("/moved/robots.txt", http.StatusMovedPermanently))
+  mux.HandleFunc("/moved/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+    fetches.Add(1)
+    if status != http.StatusOK {
+      w.WriteHeader(status)
+      return
+    }
+    w.Write([]byte("User-agent: gator\nDisallow: /private/\n"))
+  })
+
+  server := httptest.NewServer(mux)
+  defer server.Close()
+
+  target := func(path string) *url.URL {
+    parsed, err := url.Parse(server.URL + path)
+    if err != nil {
+      t.Fatalf("url.Parse: %v", err)
+    }
+    return parsed
+  }
+
+  // A redirected robots.txt still counts.
+  robots := newTestRobotsCache()
+  if err := robots.check(context.Background(), target("/feed.xml")); err != nil {
+    t.Errorf("check(/feed.xml) = %v; want allowed", err)
+  }
+  if err := robots.check(context.Background(), target("/private/feed.xml")); err == nil {
+    t.Errorf("check(/private/feed.xml) should be disallowed by the redirected robots.txt")
+  }
+  if fetches.Load() != 1 {
+    t.Errorf("robots.txt fetched %d times; want it cached after one", fetches.Load())
+  }
+
+  // A server error blocks the request and is asked again next time.
+  status = http.StatusServiceUnavailable
+  fetches.Store(0)
+  robots = newTestRobotsCache()
+  for i := 0; i < 2; i++ {
+    if err := robots.check(context.Background(), target("/feed.xml")); err == nil {
+      t.Errorf("check with robots.txt answering 503 should fail")
+    }
+  }
+  if fetches.Load() != 2 {
+    t.Errorf("robots.txt fetched %d times; a 503 must not be cached", fetches.Load())
+  }
+
+  // A missing robots.txt allows everything and is cached.
+  status = http.StatusNotFound
+  fetches.Store(0)
+  robots = newTestRobotsCache()
+  for i := 0; i < 2; i++ {
+    if err := robots.check(context.Background(), target("/private/feed.xml")); err != nil {
+      t.Errorf("check with no robots.txt = %v; want allowed", err)
+    }
+  }
+  if fetches.Load() != 1 {
+    t.Errorf("robots.txt fetched %d times; want a 404 cached after one", fetches.Load())
+  }
+}
+
+func TestRobotsCacheUnreachable(t *testing.T) {
+
+  server := httptest.NewServer(http.NotFoundHandler())
+  target, err := url.Parse(server.URL + "/feed.xml")
+  if err != nil {
+    t.Fatalf("url.Parse: %v", err)
+  }
+  server.Close()
+
+  robots := newTestRobotsCache()
+  if err := robots.check(context.Background(), target); err == nil {
+    t.Errorf("check with robots.txt unreachable should fail")
+  }
+  if len(robots.entries) != 0 {
+    t.Errorf("an unreachable robots.txt must not be cached")
+  }
+}
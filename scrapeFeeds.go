@@ -0,0 +1,382 @@
+package main
+
+import (
+    "github.com/John-1005/BlogAggregator/internal/database"
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "strconv"
+    "sync"
+    "sync/atomic"
+    "syscall"
+    "time"
+)
+
+// feedClaimLease is how long a claimed feed stays reserved for this process.
+// If we die mid-fetch the lease runs out and another aggregator picks it up.
+const feedClaimLease = 10 * time.Minute
+
+// aggShutdownTimeout is how long fetches already in flight get to finish
+// after agg is asked to stop before they are cancelled too.
+const aggShutdownTimeout = 30 * time.Second
+
+type aggStats struct {
+  feeds  atomic.Int64
+  failed atomic.Int64
+  posts  atomic.Int64
+  downloads atomic.Int64
+}
+
+func handlerAgg(s* state, cmd command) error {
+  if len(cmd.args) == 0 {
+    return fmt.Errorf("expected command")
+  }
+
+  durationString := cmd.args[0]
+
+  duration, err := time.ParseDuration(durationString)
+
+  if err != nil {
+    return err
+  }
+
+  concurrency := 1
+  if len(cmd.args) > 1 {
+    concurrency, err = strconv.Atoi(cmd.args[1])
+    if err != nil || concurrency < 1 {
+      return fmt.Errorf("invalid concurrency: %s", cmd.args[1])
+    }
+  }
+
+  batchSize := concurrency
+  if len(cmd.args) > 2 {
+    batchSize, err = strconv.Atoi(cmd.args[2])
+    if err != nil || batchSize < 1 {
+      return fmt.Errorf("invalid batch size: %s", cmd.args[2])
+    }
+  }
+
+  ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+  defer stop()
+
+  fmt.Printf("Collecting up to %d feeds every %s with %d workers\n", batchSize, duration, concurrency)
+
+  ticker := time.NewTicker(duration)
+  defer ticker.Stop()
+
+  var stats aggStats
+  started := time.Now()
+
+  // Episodes download in the background so a long one doesn't hold up
+  // polling; each round just nudges the downloader if it is idle.
+  downloadsDue := make(chan struct{}, 1)
+  var downloading sync.WaitGroup
+  downloading.Add(1)
+  go func() {
+    defer downloading.Done()
+    for range downloadsDue {
+      done, _ := runDownloads(ctx, s)
+      stats.downloads.Add(int64(done))
+    }
+  }()
+
+  for {
+    scrapeFeeds(ctx, s, concurrency, batchSize, &stats)
+
+    select {
+    case downloadsDue <- struct{}{}:
+    default:
+    }
+
+    select {
+    case <-ctx.Done():
+      close(downloadsDue)
+      downloading.Wait()
+      fmt.Printf(
+        "Shutting down after %s: %d feeds fetched, %d failed, %d new posts, %d episodes downloaded\n",
+        time.Since(started).Round(time.Second),
+        stats.feeds.Load(),
+        stats.failed.Load(),
+        stats.posts.Load(),
+        stats.downloads.Load(),
+      )
+      return nil
+    case <-ticker.C:
+    }
+  }
+}
+
+// withGracePeriod returns a context that outlives ctx by grace, so work that
+// has already started can wrap up after a shutdown request.
+func withGracePeriod(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+  graceCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+  stop := context.AfterFunc(ctx, func() {
+    time.AfterFunc(grace, cancel)
+  })
+
+  return graceCtx, func() {
+    stop()
+    cancel()
+  }
+}
+
+// scrapeFeeds claims the batchSize stalest feeds and fetches them with at
+// most concurrency requests in flight. Claiming skips rows other
+// aggregators have locked or leased, so several can share one database.
+// Once ctx is cancelled no new fetches start; those in progress get
+// aggShutdownTimeout to finish.
+func scrapeFeeds(ctx context.Context, s *state, concurrency, batchSize int, stats *aggStats) error {
+
+  now := time.Now().UTC()
+
+  feeds, err := s.db.ClaimFeedsToFetch(
+    ctx,
+    database.ClaimFeedsToFetchParams{
+      LeaseUntil: sql.NullTime{
+        Time:  now.Add(feedClaimLease),
+        Valid: true,
+      },
+      Now: sql.NullTime{
+        Time:  now,
+        Valid: true,
+      },
+      BatchSize: int32(batchSize),
+    },
+  )
+
+  if err != nil {
+    if ctx.Err() == nil {
+      log.Printf("Error claiming feeds to fetch %v", err)
+    }
+    return nil
+  }
+
+  workCtx, cancel := withGracePeriod(ctx, aggShutdownTimeout)
+  defer cancel()
+
+  jobs := make(chan database.Feed)
+  var wg sync.WaitGroup
+
+  for i := 0; i < concurrency; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for feed := range jobs {
+        posts, err := scrapeFeed(workCtx, s, feed)
+        stats.feeds.Add(1)
+        stats.posts.Add(int64(posts))
+        if err != nil {
+          stats.failed.Add(1)
+          log.Println(err)
+        }
+      }
+    }()
+  }
+
+  for i, feed := range feeds {
+    select {
+    case jobs <- feed:
+    case <-ctx.Done():
+      // Hand back the claims we never got to so other aggregators can.
+      for _, skipped := range feeds[i:] {
+        err := s.db.ReleaseFeedClaim(workCtx, skipped.ID)
+        if err != nil {
+          log.Printf("Error releasing claim on feed %s: %v", skipped.Url, err)
+        }
+      }
+      close(jobs)
+      wg.Wait()
+      return nil
+    }
+  }
+  close(jobs)
+
+  wg.Wait()
+  return nil
+}
+
+// scrapeFeed fetches one feed, stores its new items and updates the ones that
+// changed, returning how many posts were created.
+func scrapeFeed(ctx context.Context, s *state, fetchedFeed database.Feed) (int, error) {
+
+  // Marking also releases our claim, so do it however the fetch turns out.
+  defer func() {
+    _, err := s.db.MarkedFeedFetch(
+      ctx,
+      database.MarkedFeedFetchParams{
+        Now: sql.NullTime{
+          Time:  time.Now().UTC(),
+          Valid: true,
+        },
+        ID: fetchedFeed.ID,
+      },
+    )
+    if err != nil {
+      log.Printf("Error marking feed %s as fetched %v ", fetchedFeed.Url, err)
+    }
+  }()
+
+  result, err := fetchFeed(ctx, s.client, fetchedFeed.Url, fetchedFeed.Etag.String, fetchedFeed.LastModified.String)
+
+  if err != nil {
+    if errors.Is(err, errFeedGone) {
+      markFeedGone(ctx, s, fetchedFeed, err)
+    } else if ctx.Err() == nil {
+      // Being cancelled on shutdown says nothing about the feed itself.
+      recordFeedFailure(ctx, s, fetchedFeed, err)
+    }
+    return 0, fmt.Errorf("Error fetching feed %s: %w", fetchedFeed.Url, err)
+  }
+
+  if newURL, reason := movedFeedURL(ctx, s, fetchedFeed.Url, result); newURL != "" {
+    feedID, err := moveFeed(ctx, s, fetchedFeed, newURL, reason)
+    if err != nil {
+      log.Printf("Error moving feed %s to %s: %v", fetchedFeed.Url, newURL, err)
+    } else {
+      // The deferred mark and everything below now apply to the feed that
+      // owns the new URL.
+      fetchedFeed.ID = feedID
+      fetchedFeed.Url = newURL
+    }
+  }
+
+  err = s.db.RecordFeedSuccess(
+    ctx,
+    database.RecordFeedSuccessParams{
+      Now: sql.NullTime{
+        Time:  time.Now().UTC(),
+        Valid: true,
+      },
+      ID: fetchedFeed.ID,
+    },
+  )
+  if err != nil {
+    log.Printf("Error recording success for feed %s: %v", fetchedFeed.Url, err)
+  }
+
+  err = s.db.SetFeedCacheHeaders(
+    ctx,
+    database.SetFeedCacheHeadersParams{
+      ID: fetchedFeed.ID,
+      Etag: sql.NullString{
+        String: result.ETag,
+        Valid: result.ETag != "",
+      },
+      LastModified: sql.NullString{
+        String: result.LastModified,
+        Valid: result.LastModified != "",
+      },
+    },
+  )
+
+  if err != nil {
+    log.Printf("Error saving cache headers for feed %s: %v", fetchedFeed.Url, err)
+  }
+
+  if result.NotModified {
+    scheduleNextFetch(ctx, s, fetchedFeed, result)
+    log.Printf("Feed %s not modified since last fetch", fetchedFeed.Url)
+    return 0, nil
+  }
+
+  feed := result.Feed
+
+  err = updateFeedMetadata(ctx, s, fetchedFeed.ID, result.FinalURL, feed)
+  if err != nil {
+    log.Printf("Error updating feed %s: %v", fetchedFeed.Url, err)
+  }
+
+  created := 0
+
+  updated := 0
+
+  for _, item := range feed.Channel.Item {
+
+    outcome, err := storePost(ctx, s, fetchedFeed.ID, item)
+    if err != nil {
+      if ctx.Err() != nil {
+        return created, fmt.Errorf("Stopped storing posts for feed %s: %w", fetchedFeed.Url, ctx.Err())
+      }
+      log.Printf("Couldn't store post: %v", err)
+      continue
+    }
+
+    switch outcome {
+    case postCreated:
+      created++
+    case postUpdated:
+      updated++
+    }
+  }
+
+  scheduleNextFetch(ctx, s, fetchedFeed, result)
+
+  log.Printf("Feed %s collected, %v posts found, %d new, %d updated", feed.Channel.Title, len(feed.Channel.Item), created, updated)
+  return created, nil
+}
+
+// scheduleNextFetch sets when the feed becomes due again. It runs after the
+// posts are stored so their times feed into the adaptive interval.
+func scheduleNextFetch(ctx context.Context, s *state, fetchedFeed database.Feed, result fetchResult) {
+
+  now := time.Now().UTC()
+  postInterval := updatePostInterval(ctx, s, fetchedFeed, now)
+
+  // A 304 carries no feed; go by what it said when it last sent one.
+  hints := storedRefreshHints(fetchedFeed)
+  if result.Feed != nil {
+    hints = feedRefreshHints(result.Feed)
+  }
+
+  minInterval, maxInterval := s.cfg.PollIntervalBounds()
+  nextFetch := nextFetchTime(now, hints, result.FreshUntil, postInterval, minInterval, maxInterval)
+
+  err := s.db.SetFeedNextFetch(
+    ctx,
+    database.SetFeedNextFetchParams{
+      ID: fetchedFeed.ID,
+      NextFetchAt: sql.NullTime{
+        Time:  nextFetch,
+        Valid: true,
+      },
+    },
+  )
+
+  if err != nil {
+    log.Printf("Error scheduling next fetch for feed %s: %v", fetchedFeed.Url, err)
+  }
+}
+
+// recordFeedFailure bumps the feed's failure count, which backs off its next
+// fetch and disables it once the configured limit is reached.
+func recordFeedFailure(ctx context.Context, s *state, fetchedFeed database.Feed, fetchErr error) {
+
+  feed, err := s.db.RecordFeedFailure(
+    ctx,
+    database.RecordFeedFailureParams{
+      ID: fetchedFeed.ID,
+      LastError: sql.NullString{
+        String: fetchErr.Error(),
+        Valid: true,
+      },
+      MaxFailures: int32(s.cfg.FeedFailureLimit()),
+      Now: sql.NullTime{
+        Time:  time.Now().UTC(),
+        Valid: true,
+      },
+    },
+  )
+  if err != nil {
+    log.Printf("Error recording failure for feed %s: %v", fetchedFeed.Url, err)
+    return
+  }
+
+  if feed.DisabledAt.Valid && !fetchedFeed.DisabledAt.Valid {
+    log.Printf("Feed %s disabled after %d consecutive failures", feed.Url, feed.ConsecutiveFailures)
+  }
+}
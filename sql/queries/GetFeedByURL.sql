@@ -1,3 +1,11 @@
 -- name: GetFeedByUrl :one
 SELECT id FROM feeds
-WHERE url = $1;
+WHERE canonical_url = $1;
+
+
+-- name: GetFeedInfo :one
+SELECT feeds.*, users.name AS created_by,
+  (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS followers
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.canonical_url = $1;
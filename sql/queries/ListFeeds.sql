@@ -1,4 +1,4 @@
 -- name: ListFeeds :many
-SELECT feeds.name as feed_name, feeds.url, users.name
+SELECT feeds.name as feed_name, feeds.url, users.name, feeds.consecutive_failures, feeds.last_error, feeds.disabled_at, feeds.title, feeds.site_link, feeds.language
 FROM feeds
 JOIN users ON feeds.user_id = users.id;
@@ -1,14 +1,78 @@
 
 -- name: MarkedFeedFetch :one
+-- now comes from Go, like the now ClaimFeedsToFetch compares it with: NOW()
+-- is in the session's time zone, which needn't be UTC.
 UPDATE feeds
-SET last_fetched_at = NOW(),
-updated_at = NOW()
-WHERE id = $1
+SET last_fetched_at = sqlc.arg(now),
+updated_at = sqlc.arg(now),
+claimed_until = NULL
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
 
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds
-ORDER BY last_fetched_at ASC Nulls FIRST
-LIMIT 1;
+-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET claimed_until = sqlc.arg(lease_until)
+WHERE id IN (
+  SELECT due.id FROM feeds AS due
+  WHERE (due.claimed_until IS NULL OR due.claimed_until < sqlc.arg(now))
+  AND due.disabled_at IS NULL
+  AND (due.next_fetch_at IS NULL OR due.next_fetch_at <= sqlc.arg(now))
+  AND (
+    due.consecutive_failures = 0
+    OR due.last_fetched_at IS NULL
+    -- 2^11 minutes is already past the cap; a larger exponent could overflow.
+    OR due.last_fetched_at + LEAST(POWER(2, LEAST(due.consecutive_failures, 11)) * INTERVAL '1 minute', INTERVAL '1 day') < sqlc.arg(now)
+  )
+  ORDER BY due.next_fetch_at ASC Nulls FIRST, due.last_fetched_at ASC Nulls FIRST
+  LIMIT sqlc.arg(batch_size)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+
+-- name: SetFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2,
+last_modified = $3
+WHERE id = $1;
+
+
+-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL
+WHERE id = $1;
+
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+last_error = NULL,
+last_success_at = sqlc.arg(now)
+WHERE id = sqlc.arg(id);
+
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+last_error = sqlc.arg(last_error),
+disabled_at = CASE
+  WHEN consecutive_failures + 1 >= sqlc.arg(max_failures)::integer THEN sqlc.arg(now)
+  ELSE disabled_at
+END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+
+-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1;
+
+
+-- name: MarkFeedGone :exec
+UPDATE feeds
+SET disabled_at = sqlc.arg(now),
+last_error = sqlc.arg(last_error)
+WHERE id = sqlc.arg(id);
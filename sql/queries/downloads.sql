@@ -0,0 +1,145 @@
+-- name: EnableFeedDownloads :exec
+INSERT INTO feed_downloads (feed_id, created_at, keep_episodes)
+VALUES ($1, $2, $3)
+ON CONFLICT (feed_id) DO UPDATE
+SET keep_episodes = EXCLUDED.keep_episodes;
+
+
+-- name: DisableFeedDownloads :execrows
+DELETE FROM feed_downloads
+WHERE feed_id = $1;
+
+
+-- name: CancelFeedDownloads :many
+DELETE FROM downloads
+USING post_enclosures, posts
+WHERE downloads.enclosure_id = post_enclosures.id
+AND post_enclosures.post_id = posts.id
+AND posts.feed_id = $1
+AND downloads.status <> 'done'
+RETURNING downloads.path;
+
+
+-- name: ListFeedDownloads :many
+SELECT feed_downloads.*, feeds.name AS feed_name, feeds.url
+FROM feed_downloads
+JOIN feeds ON feed_downloads.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name;
+
+
+-- name: QueueDownloads :execrows
+INSERT INTO downloads (id, enclosure_id, created_at, updated_at)
+SELECT gen_random_uuid(), ranked.id, sqlc.arg(now), sqlc.arg(now)
+FROM (
+  SELECT post_enclosures.id,
+  DENSE_RANK() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC, posts.id) AS position,
+  feed_downloads.keep_episodes
+  FROM post_enclosures
+  JOIN posts ON post_enclosures.post_id = posts.id
+  JOIN feed_downloads ON posts.feed_id = feed_downloads.feed_id
+) AS ranked
+WHERE ranked.position <= ranked.keep_episodes
+ON CONFLICT (enclosure_id) DO NOTHING;
+
+
+-- name: ClaimDownload :one
+UPDATE downloads
+SET status = 'downloading',
+claimed_until = sqlc.arg(lease_until),
+attempts = attempts + 1,
+updated_at = sqlc.arg(now)
+WHERE id = (
+  SELECT downloads.id FROM downloads
+  JOIN post_enclosures ON downloads.enclosure_id = post_enclosures.id
+  JOIN posts ON post_enclosures.post_id = posts.id
+  JOIN feed_downloads ON posts.feed_id = feed_downloads.feed_id
+  WHERE downloads.status IN ('queued', 'downloading')
+  AND (downloads.claimed_until IS NULL OR downloads.claimed_until < sqlc.arg(now))
+  ORDER BY downloads.created_at
+  LIMIT 1
+  FOR UPDATE OF downloads SKIP LOCKED
+)
+RETURNING *;
+
+
+-- name: GetDownloadSource :one
+SELECT post_enclosures.url, post_enclosures.mime_type, post_enclosures.length, posts.title, feeds.name AS feed_name
+FROM post_enclosures
+JOIN posts ON post_enclosures.post_id = posts.id
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE post_enclosures.id = $1;
+
+
+-- name: SetDownloadProgress :execrows
+UPDATE downloads
+SET path = $2,
+bytes_downloaded = $3,
+total_bytes = $4,
+claimed_until = $5,
+updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: CompleteDownload :execrows
+UPDATE downloads
+SET status = 'done',
+bytes_downloaded = $2,
+total_bytes = $2,
+completed_at = NOW(),
+updated_at = NOW(),
+claimed_until = NULL,
+last_error = NULL
+WHERE id = $1;
+
+
+-- name: RecordDownloadFailure :exec
+UPDATE downloads
+SET status = $2,
+last_error = $3,
+claimed_until = $4,
+updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: GetExpiredDownloads :many
+SELECT ranked.id, ranked.path
+FROM (
+  SELECT downloads.id, downloads.path,
+  DENSE_RANK() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC, posts.id) AS position,
+  feed_downloads.keep_episodes
+  FROM downloads
+  JOIN post_enclosures ON downloads.enclosure_id = post_enclosures.id
+  JOIN posts ON post_enclosures.post_id = posts.id
+  JOIN feed_downloads ON posts.feed_id = feed_downloads.feed_id
+  WHERE downloads.status = 'done'
+) AS ranked
+WHERE ranked.position > ranked.keep_episodes;
+
+
+-- name: MarkDownloadRemoved :exec
+UPDATE downloads
+SET status = 'removed',
+updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: ListDownloads :many
+SELECT downloads.*, post_enclosures.url, posts.title, feeds.name AS feed_name
+FROM downloads
+JOIN post_enclosures ON downloads.enclosure_id = post_enclosures.id
+JOIN posts ON post_enclosures.post_id = posts.id
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+AND downloads.status <> 'removed'
+ORDER BY downloads.created_at DESC;
+
+
+-- name: MoveFeedDownloads :exec
+INSERT INTO feed_downloads (feed_id, created_at, keep_episodes)
+SELECT sqlc.arg(to_feed_id)::uuid, moved.created_at, moved.keep_episodes
+FROM feed_downloads AS moved
+WHERE moved.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (feed_id) DO NOTHING;
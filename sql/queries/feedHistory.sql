@@ -0,0 +1,94 @@
+-- name: CreateFeedHistory :exec
+INSERT INTO feed_history (id, feed_id, created_at, old_url, new_url, reason)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+canonical_url = $3,
+updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), moved.created_at, NOW(), moved.user_id, sqlc.arg(to_feed_id)::uuid
+FROM feed_follows AS moved
+WHERE moved.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (feed_id, user_id) DO NOTHING;
+
+
+-- name: MoveFeedPosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE posts.feed_id = sqlc.arg(from_feed_id)
+AND posts.guid NOT IN (
+  SELECT kept.guid FROM posts AS kept WHERE kept.feed_id = sqlc.arg(to_feed_id)
+);
+
+
+-- name: FoldFeedPostEnclosures :exec
+UPDATE post_enclosures
+SET post_id = kept.id
+FROM posts AS dropped
+JOIN posts AS kept ON kept.guid = dropped.guid
+WHERE post_enclosures.post_id = dropped.id
+AND dropped.feed_id = sqlc.arg(from_feed_id)
+AND kept.feed_id = sqlc.arg(to_feed_id)
+AND NOT EXISTS (
+  SELECT 1 FROM post_enclosures AS existing
+  WHERE existing.post_id = kept.id
+  AND existing.url = post_enclosures.url
+);
+
+
+-- name: FoldFeedPostDownloads :exec
+UPDATE downloads
+SET enclosure_id = kept_enclosure.id
+FROM post_enclosures AS dropped_enclosure
+JOIN posts AS dropped ON dropped_enclosure.post_id = dropped.id
+JOIN posts AS kept ON kept.guid = dropped.guid
+JOIN post_enclosures AS kept_enclosure ON kept_enclosure.post_id = kept.id AND kept_enclosure.url = dropped_enclosure.url
+WHERE downloads.enclosure_id = dropped_enclosure.id
+AND dropped.feed_id = sqlc.arg(from_feed_id)
+AND kept.feed_id = sqlc.arg(to_feed_id)
+AND NOT EXISTS (
+  SELECT 1 FROM downloads AS existing
+  WHERE existing.enclosure_id = kept_enclosure.id
+);
+
+
+-- name: FoldFeedPostRevisions :exec
+UPDATE post_revisions
+SET post_id = kept.id
+FROM posts AS dropped
+JOIN posts AS kept ON kept.guid = dropped.guid
+WHERE post_revisions.post_id = dropped.id
+AND dropped.feed_id = sqlc.arg(from_feed_id)
+AND kept.feed_id = sqlc.arg(to_feed_id);
+
+
+-- name: GetFeedPostDownloads :many
+SELECT downloads.path
+FROM downloads
+JOIN post_enclosures ON downloads.enclosure_id = post_enclosures.id
+JOIN posts ON post_enclosures.post_id = posts.id
+WHERE posts.feed_id = $1
+AND downloads.path IS NOT NULL;
+
+
+-- name: DeleteFeedPosts :exec
+DELETE FROM posts
+WHERE feed_id = $1;
+
+
+-- name: MoveFeedHistory :exec
+UPDATE feed_history
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
@@ -0,0 +1,27 @@
+-- name: GetRecentPostTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1
+AND published_at IS NOT NULL
+AND NOT published_at_inferred
+ORDER BY published_at DESC
+LIMIT $2;
+
+
+-- name: SetFeedPostInterval :exec
+UPDATE feeds
+SET post_interval_seconds = $2
+WHERE id = $1;
+
+
+-- name: GetFeedStats :many
+SELECT feeds.name, feeds.url, feeds.last_fetched_at, feeds.next_fetch_at, feeds.post_interval_seconds,
+  (SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS post_count,
+  latest.published_at AS latest_post_at
+FROM feeds
+LEFT JOIN LATERAL (
+  SELECT published_at FROM posts
+  WHERE posts.feed_id = feeds.id
+  ORDER BY published_at DESC NULLS LAST
+  LIMIT 1
+) latest ON TRUE
+ORDER BY feeds.name;
@@ -2,13 +2,30 @@
 
 
 -- name: CreateFeed :one
-INSERT INTO feeds(id, user_id, name, created_at, updated_at, url)
+INSERT INTO feeds(id, user_id, name, created_at, updated_at, url, canonical_url)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
 Returning *;
+
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2,
+description = $3,
+site_link = $4,
+image_url = $5,
+language = $6,
+generator = $7,
+ttl = $8,
+update_period = $9,
+update_frequency = $10,
+skip_hours = $11,
+skip_days = $12
+WHERE id = $1;
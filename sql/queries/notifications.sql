@@ -0,0 +1,19 @@
+-- name: NotifyFeedFollowers :exec
+INSERT INTO notifications (id, user_id, created_at, message)
+SELECT gen_random_uuid(), user_id, NOW(), sqlc.arg(message)::text
+FROM feed_follows
+WHERE feed_id = sqlc.arg(feed_id);
+
+
+-- name: GetUnreadNotifications :many
+SELECT * FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL
+ORDER BY created_at;
+
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
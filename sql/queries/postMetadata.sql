@@ -0,0 +1,70 @@
+-- name: DeleteStalePostAuthors :exec
+DELETE FROM post_authors
+WHERE post_id = sqlc.arg(post_id)
+AND NOT (name = ANY(sqlc.arg(names)::text[]));
+
+
+-- name: CreatePostAuthor :exec
+INSERT INTO post_authors (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+
+-- name: GetPostAuthors :many
+SELECT name FROM post_authors
+WHERE post_id = $1
+ORDER BY name;
+
+
+-- name: DeleteStalePostCategories :exec
+DELETE FROM post_categories
+WHERE post_id = sqlc.arg(post_id)
+AND NOT (name = ANY(sqlc.arg(names)::text[]));
+
+
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+
+-- name: GetPostCategories :many
+SELECT name FROM post_categories
+WHERE post_id = $1
+ORDER BY name;
+
+
+-- name: UpsertPostEnclosure :exec
+INSERT INTO post_enclosures (id, post_id, url, mime_type, length)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (post_id, url) DO UPDATE
+SET mime_type = EXCLUDED.mime_type,
+length = EXCLUDED.length
+WHERE (post_enclosures.mime_type, post_enclosures.length) IS DISTINCT FROM (EXCLUDED.mime_type, EXCLUDED.length);
+
+
+-- name: UpdatePostEnclosureURL :exec
+UPDATE post_enclosures
+SET url = $2
+WHERE id = $1;
+
+
+-- name: GetStaleEnclosureDownloads :many
+SELECT downloads.path
+FROM downloads
+JOIN post_enclosures ON downloads.enclosure_id = post_enclosures.id
+WHERE post_enclosures.post_id = sqlc.arg(post_id)
+AND NOT (post_enclosures.url = ANY(sqlc.arg(urls)::text[]))
+AND downloads.path IS NOT NULL;
+
+
+-- name: DeleteStalePostEnclosures :exec
+DELETE FROM post_enclosures
+WHERE post_id = sqlc.arg(post_id)
+AND NOT (url = ANY(sqlc.arg(urls)::text[]));
+
+
+-- name: GetPostEnclosures :many
+SELECT * FROM post_enclosures
+WHERE post_id = $1
+ORDER BY url;
@@ -0,0 +1,20 @@
+-- name: SavePostRevision :execrows
+INSERT INTO post_revisions (id, post_id, created_at, title, description, content, content_hash)
+SELECT sqlc.arg(id)::uuid, posts.id, sqlc.arg(created_at)::timestamp, posts.title, posts.description, posts.content, posts.content_hash
+FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)
+AND posts.guid = sqlc.arg(guid)
+AND posts.content_hash <> ''
+AND posts.content_hash <> sqlc.arg(content_hash);
+
+
+-- name: GetPostRevisionsByURL :many
+SELECT post_revisions.*, feeds.name AS feed_name
+FROM post_revisions
+JOIN posts ON post_revisions.post_id = posts.id
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.canonical_url = sqlc.arg(canonical_url)
+-- A post keyed by its link has it as its guid, and may have been stored
+-- under another link since.
+OR posts.guid = sqlc.arg(canonical_url)
+ORDER BY post_revisions.created_at DESC;
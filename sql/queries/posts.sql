@@ -1,15 +1,56 @@
--- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING *;
+-- name: UpsertPost :one
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, content, guid, content_hash, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+url = EXCLUDED.url,
+canonical_url = EXCLUDED.canonical_url,
+description = EXCLUDED.description,
+content = EXCLUDED.content,
+content_hash = EXCLUDED.content_hash,
+-- Rows from before content hashing get their hash without counting as edited.
+updated_at = CASE WHEN posts.content_hash = '' THEN posts.updated_at ELSE EXCLUDED.updated_at END
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id;
 --
 
 
+-- name: RekeyPostByLink :exec
+UPDATE posts
+SET guid = sqlc.arg(guid)
+WHERE posts.feed_id = sqlc.arg(feed_id)
+AND posts.guid = sqlc.arg(link_guid)
+AND posts.guid <> sqlc.arg(guid)
+AND NOT EXISTS (
+  SELECT 1 FROM posts AS keyed
+  WHERE keyed.feed_id = sqlc.arg(feed_id)
+  AND keyed.guid = sqlc.arg(guid)
+);
+
+
+-- name: GetPostID :one
+SELECT id FROM posts
+WHERE feed_id = $1
+AND guid = $2;
+
+
 -- name: GetPostsForUser :many
 SELECT posts.*, feeds.name AS feed_name from posts
 JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 JOIN feeds  ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(author)::text IS NULL OR EXISTS (
+  SELECT 1 FROM post_authors
+  WHERE post_authors.post_id = posts.id AND post_authors.name ILIKE sqlc.narg(author)
+))
+AND (sqlc.narg(category)::text IS NULL OR EXISTS (
+  SELECT 1 FROM post_categories
+  WHERE post_categories.post_id = posts.id AND post_categories.name ILIKE sqlc.narg(category)
+))
+AND (NOT sqlc.arg(with_enclosures)::boolean OR EXISTS (
+  SELECT 1 FROM post_enclosures
+  WHERE post_enclosures.post_id = posts.id
+))
 ORDER BY posts.published_at DESC
-LIMIT $2;
+LIMIT sqlc.arg('limit');
 --
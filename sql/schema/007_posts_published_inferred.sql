@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN published_at_inferred BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN published_at_inferred;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN claimed_until TIMESTAMP;
+
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN claimed_until;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN post_interval_seconds BIGINT;
+
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN post_interval_seconds;
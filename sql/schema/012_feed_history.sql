@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE feed_history (
+  id UUID PRIMARY KEY,
+  feed_id UUID NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  old_url TEXT NOT NULL,
+  new_url TEXT NOT NULL,
+  reason TEXT NOT NULL,
+  FOREIGN KEY (feed_id)
+  REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+
+-- +goose Down
+DROP TABLE feed_history;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE notifications (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  message TEXT NOT NULL,
+  read_at TIMESTAMP,
+  FOREIGN KEY (user_id)
+  REFERENCES users(id) ON DELETE CASCADE
+);
+
+
+-- +goose Down
+DROP TABLE notifications;
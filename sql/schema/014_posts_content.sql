@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content TEXT;
+
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE post_revisions (
+  id UUID PRIMARY KEY,
+  post_id UUID NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT,
+  content TEXT,
+  content_hash TEXT NOT NULL,
+  FOREIGN KEY (post_id)
+  REFERENCES posts(id) ON DELETE CASCADE
+);
+
+
+-- +goose Down
+DROP TABLE post_revisions;
+
+ALTER TABLE posts
+DROP COLUMN content_hash;
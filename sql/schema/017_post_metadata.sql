@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE post_authors (
+  post_id UUID NOT NULL,
+  name TEXT NOT NULL,
+  PRIMARY KEY (post_id, name),
+  FOREIGN KEY (post_id)
+  REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE post_categories (
+  post_id UUID NOT NULL,
+  name TEXT NOT NULL,
+  PRIMARY KEY (post_id, name),
+  FOREIGN KEY (post_id)
+  REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE post_enclosures (
+  id UUID PRIMARY KEY,
+  post_id UUID NOT NULL,
+  url TEXT NOT NULL,
+  mime_type TEXT,
+  length BIGINT,
+  UNIQUE (post_id, url),
+  FOREIGN KEY (post_id)
+  REFERENCES posts(id) ON DELETE CASCADE
+);
+
+
+-- +goose Down
+DROP TABLE post_enclosures;
+
+DROP TABLE post_categories;
+
+DROP TABLE post_authors;
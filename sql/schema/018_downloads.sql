@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE feed_downloads (
+  feed_id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  keep_episodes INTEGER NOT NULL,
+  FOREIGN KEY (feed_id)
+  REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TABLE downloads (
+  id UUID PRIMARY KEY,
+  enclosure_id UUID NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  -- queued, downloading, done, failed or removed (pruned by retention).
+  status TEXT NOT NULL DEFAULT 'queued',
+  path TEXT,
+  bytes_downloaded BIGINT NOT NULL DEFAULT 0,
+  total_bytes BIGINT,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  -- The lease while downloading, or when to retry a queued download.
+  claimed_until TIMESTAMP,
+  completed_at TIMESTAMP,
+  FOREIGN KEY (enclosure_id)
+  REFERENCES post_enclosures(id) ON DELETE CASCADE
+);
+
+
+-- +goose Down
+DROP TABLE downloads;
+
+DROP TABLE feed_downloads;
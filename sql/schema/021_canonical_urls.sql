@@ -0,0 +1,199 @@
+-- +goose Up
+-- A copy of canonicalURL in canonicalURL.go, only needed to backfill.
+-- +goose StatementBegin
+CREATE FUNCTION gator_canonical_url(raw TEXT) RETURNS TEXT AS $$
+DECLARE
+  parts TEXT[];
+  host TEXT;
+  query TEXT;
+BEGIN
+  parts := regexp_match(btrim(raw, E' \t\r\n'), '^[Hh][Tt][Tt][Pp][Ss]?://([^/?#]*)([^?#]*)(?:\?([^#]*))?');
+  IF parts IS NULL THEN
+    RETURN btrim(raw, E' \t\r\n');
+  END IF;
+
+  host := lower(parts[1]);
+  host := regexp_replace(host, '^www\.', '');
+  host := regexp_replace(host, ':80$', '');
+  host := regexp_replace(host, ':443$', '');
+
+  SELECT string_agg(param, '&' ORDER BY param COLLATE "C") INTO query
+  FROM unnest(string_to_array(COALESCE(parts[3], ''), '&')) AS param
+  WHERE param <> ''
+  AND param !~* '^(utm_[^=]*|fbclid|gclid|mc_cid|mc_eid)(=|$)';
+
+  RETURN 'https://' || host || rtrim(parts[2], '/') || COALESCE('?' || query, '');
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- Folds each post in post_merges into the one replacing it before the
+-- former is deleted. Enclosures the surviving post lacks move across with
+-- their downloads; for one both have, the dropped post's download moves over
+-- if the survivor has none, preferring finished ones. Only when both were
+-- downloaded is a download lost, and its file is left in the download
+-- directory.
+-- +goose StatementBegin
+CREATE FUNCTION gator_fold_post_merges() RETURNS VOID AS $$
+BEGIN
+  UPDATE post_enclosures
+  SET post_id = post_merges.to_id
+  FROM post_merges
+  WHERE post_enclosures.post_id = post_merges.from_id
+  AND post_enclosures.id IN (
+    SELECT DISTINCT ON (post_merges.to_id, dropped.url) dropped.id
+    FROM post_enclosures AS dropped
+    JOIN post_merges ON dropped.post_id = post_merges.from_id
+    LEFT JOIN downloads ON downloads.enclosure_id = dropped.id
+    WHERE NOT EXISTS (
+      SELECT 1 FROM post_enclosures AS kept
+      WHERE kept.post_id = post_merges.to_id
+      AND kept.url = dropped.url
+    )
+    ORDER BY post_merges.to_id, dropped.url, downloads.status = 'done' DESC NULLS LAST
+  );
+
+  UPDATE downloads
+  SET enclosure_id = moves.kept_id
+  FROM (
+    SELECT DISTINCT ON (kept.id) downloads.id AS download_id, kept.id AS kept_id
+    FROM downloads
+    JOIN post_enclosures AS dropped ON downloads.enclosure_id = dropped.id
+    JOIN post_merges ON dropped.post_id = post_merges.from_id
+    JOIN post_enclosures AS kept ON kept.post_id = post_merges.to_id AND kept.url = dropped.url
+    WHERE NOT EXISTS (
+      SELECT 1 FROM downloads AS existing
+      WHERE existing.enclosure_id = kept.id
+    )
+    ORDER BY kept.id, downloads.status = 'done' DESC, downloads.created_at
+  ) AS moves
+  WHERE downloads.id = moves.download_id;
+
+  UPDATE post_revisions
+  SET post_id = post_merges.to_id
+  FROM post_merges
+  WHERE post_revisions.post_id = post_merges.from_id;
+
+  DELETE FROM posts
+  WHERE id IN (SELECT from_id FROM post_merges);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TEMPORARY TABLE post_merges (
+  from_id UUID PRIMARY KEY,
+  to_id UUID NOT NULL
+);
+
+ALTER TABLE feeds
+ADD COLUMN canonical_url TEXT;
+
+UPDATE feeds SET canonical_url = gator_canonical_url(url);
+
+-- Feeds that turn out to be the same one are merged into the oldest, much as
+-- moveFeed merges a feed into one that already has its new URL: follows,
+-- posts, history and download settings move over, the merge is recorded in
+-- feed_history and followers are told.
+CREATE TEMPORARY TABLE feed_merges AS
+SELECT id AS from_id, url AS from_url, to_id, to_url
+FROM (
+  SELECT id, url,
+  FIRST_VALUE(id) OVER (PARTITION BY canonical_url ORDER BY created_at, id) AS to_id,
+  FIRST_VALUE(url) OVER (PARTITION BY canonical_url ORDER BY created_at, id) AS to_url
+  FROM feeds
+) AS ranked
+WHERE id <> to_id;
+
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), feed_follows.created_at, NOW(), feed_follows.user_id, feed_merges.to_id
+FROM feed_follows
+JOIN feed_merges ON feed_follows.feed_id = feed_merges.from_id
+ON CONFLICT (feed_id, user_id) DO NOTHING;
+
+-- Of the posts the target doesn't have yet, move one per guid.
+UPDATE posts
+SET feed_id = feed_merges.to_id
+FROM feed_merges
+WHERE posts.feed_id = feed_merges.from_id
+AND posts.id IN (
+  SELECT DISTINCT ON (feed_merges.to_id, posts.guid) posts.id
+  FROM posts
+  JOIN feed_merges ON posts.feed_id = feed_merges.from_id
+  WHERE NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = feed_merges.to_id
+    AND existing.guid = posts.guid
+  )
+  ORDER BY feed_merges.to_id, posts.guid, posts.created_at
+);
+
+-- Every post left behind now has a counterpart with its guid on the target.
+INSERT INTO post_merges (from_id, to_id)
+SELECT posts.id, kept.id
+FROM posts
+JOIN feed_merges ON posts.feed_id = feed_merges.from_id
+JOIN posts AS kept ON kept.feed_id = feed_merges.to_id AND kept.guid = posts.guid;
+
+SELECT gator_fold_post_merges();
+
+UPDATE feed_history
+SET feed_id = feed_merges.to_id
+FROM feed_merges
+WHERE feed_history.feed_id = feed_merges.from_id;
+
+INSERT INTO feed_downloads (feed_id, created_at, keep_episodes)
+SELECT feed_merges.to_id, feed_downloads.created_at, feed_downloads.keep_episodes
+FROM feed_downloads
+JOIN feed_merges ON feed_downloads.feed_id = feed_merges.from_id
+ON CONFLICT (feed_id) DO NOTHING;
+
+INSERT INTO feed_history (id, feed_id, created_at, old_url, new_url, reason)
+SELECT gen_random_uuid(), to_id, NOW(), from_url, to_url, 'merged into existing feed: same canonical URL'
+FROM feed_merges;
+
+INSERT INTO notifications (id, user_id, created_at, message)
+SELECT gen_random_uuid(), feed_follows.user_id, NOW(),
+format('Feed %s has moved to %s (%s)', feed_merges.from_url, feed_merges.to_url, 'merged into existing feed: same canonical URL')
+FROM feed_merges
+JOIN feed_follows ON feed_follows.feed_id = feed_merges.to_id;
+
+DELETE FROM feeds
+WHERE id IN (SELECT from_id FROM feed_merges);
+
+DROP TABLE feed_merges;
+
+ALTER TABLE feeds
+ALTER COLUMN canonical_url SET NOT NULL,
+ADD CONSTRAINT feeds_canonical_url_key UNIQUE (canonical_url);
+
+-- Posts identified by their link, or by a permalink guid, collapse to one
+-- post per canonical URL, folded into the first one stored.
+DELETE FROM post_merges;
+
+INSERT INTO post_merges (from_id, to_id)
+SELECT id, kept_id
+FROM (
+  SELECT id, FIRST_VALUE(id) OVER (PARTITION BY feed_id, gator_canonical_url(guid) ORDER BY created_at, id) AS kept_id
+  FROM posts
+  WHERE guid ~* '^https?://'
+) AS ranked
+WHERE id <> kept_id;
+
+SELECT gator_fold_post_merges();
+
+DROP TABLE post_merges;
+
+UPDATE posts
+SET guid = gator_canonical_url(guid)
+WHERE guid ~* '^https?://';
+
+DROP FUNCTION gator_fold_post_merges();
+
+DROP FUNCTION gator_canonical_url(TEXT);
+
+
+-- +goose Down
+-- Merged feeds and posts can't be split up again.
+ALTER TABLE feeds
+DROP CONSTRAINT feeds_canonical_url_key,
+DROP COLUMN canonical_url;
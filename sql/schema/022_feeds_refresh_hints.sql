@@ -0,0 +1,18 @@
+-- +goose Up
+-- What the feed last said about how often to poll it, kept because a 304
+-- response doesn't say it again.
+ALTER TABLE feeds
+ADD COLUMN ttl TEXT NOT NULL DEFAULT '',
+ADD COLUMN update_period TEXT NOT NULL DEFAULT '',
+ADD COLUMN update_frequency TEXT NOT NULL DEFAULT '',
+ADD COLUMN skip_hours TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN skip_days TEXT[] NOT NULL DEFAULT '{}';
+
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN ttl,
+DROP COLUMN update_period,
+DROP COLUMN update_frequency,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- The same copy of canonicalURL in canonicalURL.go as in migration 021.
+-- +goose StatementBegin
+CREATE FUNCTION gator_canonical_url(raw TEXT) RETURNS TEXT AS $$
+DECLARE
+  parts TEXT[];
+  host TEXT;
+  query TEXT;
+BEGIN
+  parts := regexp_match(btrim(raw, E' \t\r\n'), '^[Hh][Tt][Tt][Pp][Ss]?://([^/?#]*)([^?#]*)(?:\?([^#]*))?');
+  IF parts IS NULL THEN
+    RETURN btrim(raw, E' \t\r\n');
+  END IF;
+
+  host := lower(parts[1]);
+  host := regexp_replace(host, '^www\.', '');
+  host := regexp_replace(host, ':80$', '');
+  host := regexp_replace(host, ':443$', '');
+
+  SELECT string_agg(param, '&' ORDER BY param COLLATE "C") INTO query
+  FROM unnest(string_to_array(COALESCE(parts[3], ''), '&')) AS param
+  WHERE param <> ''
+  AND param !~* '^(utm_[^=]*|fbclid|gclid|mc_cid|mc_eid)(=|$)';
+
+  RETURN 'https://' || host || rtrim(parts[2], '/') || COALESCE('?' || query, '');
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+-- +goose StatementEnd
+
+-- A post's link is only part of its key when it has no guid, so it gets a
+-- canonical form of its own for looking posts up by link.
+ALTER TABLE posts
+ADD COLUMN canonical_url TEXT NOT NULL DEFAULT '';
+
+UPDATE posts SET canonical_url = gator_canonical_url(url);
+
+CREATE INDEX posts_canonical_url_idx ON posts (canonical_url);
+
+DROP FUNCTION gator_canonical_url(TEXT);
+
+
+-- +goose Down
+DROP INDEX posts_canonical_url_idx;
+
+ALTER TABLE posts
+DROP COLUMN canonical_url;